@@ -0,0 +1,53 @@
+// Package astutil contains the go/ast helper functions shared by the gentools packages.
+package astutil
+
+import (
+	"go/ast"
+)
+
+// DeclaredIdents gets the identifiers declared by given node. The declaring nodes are: not grouped declarations
+// with a single specification, type and value specifications, function declarations, struct fields and interface methods.
+func DeclaredIdents(n ast.Node) []*ast.Ident {
+	switch nt := n.(type) {
+	case *ast.GenDecl:
+		// Only the declaration with a single not grouped specification is matched as a whole.
+		if nt.Lparen.IsValid() || len(nt.Specs) != 1 {
+			return nil
+		}
+		return DeclaredIdents(nt.Specs[0])
+	case *ast.TypeSpec:
+		return []*ast.Ident{nt.Name}
+	case *ast.ValueSpec:
+		return nt.Names
+	case *ast.FuncDecl:
+		return []*ast.Ident{nt.Name}
+	case *ast.Field:
+		if len(nt.Names) != 0 {
+			return nt.Names
+		}
+		if ident := EmbeddedIdent(nt.Type); ident != nil {
+			return []*ast.Ident{ident}
+		}
+	}
+	return nil
+}
+
+// EmbeddedIdent gets the type name identifier of the embedded field.
+func EmbeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch et := expr.(type) {
+		case *ast.Ident:
+			return et
+		case *ast.StarExpr:
+			expr = et.X
+		case *ast.SelectorExpr:
+			return et.Sel
+		case *ast.IndexExpr:
+			expr = et.X
+		case *ast.IndexListExpr:
+			expr = et.X
+		default:
+			return nil
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func TestProtobufs(t *testing.T) {
-	pkgs, err := parser.LoadPackages(parser.LoadConfig{Paths: []string{"."}})
+	pkgs, _, err := parser.LoadPackages(parser.LoadConfig{Paths: []string{"."}})
 	require.NoError(t, err)
 
 	this := pkgs.MustGetByPath("github.com/kucjac/gentools/internal/integration/protobuf")
@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// isDeclared checks if the package already contains the type, function or declaration with given name.
+func (r *rootPackage) isDeclared(name string) bool {
+	if _, ok := r.refPkg.GetType(name); ok {
+		return true
+	}
+	_, ok := r.refPkg.Declarations[name]
+	return ok
+}
+
+// annotatePackage annotates the package already parsed from the same files under another build context
+// with the current build context. The package is not parsed again, as its declarations are identical.
+func (r *rootPackage) annotatePackage() {
+	if r.ctx.Err() != nil {
+		return
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.errorf("", token.NoPos, "annotating package failed: %v", rec)
+		}
+	}()
+	r.annotateBuildContext(r.typesPkg.Scope(), *r.pkgMap.build)
+}
+
+// annotateBuildContext annotates all package types, functions, methods and declarations with given build context,
+// and the build constraint of the files declaring them. The methods of the types already parsed under another
+// build context are merged with the methods defined in given build context.
+func (r *rootPackage) annotateBuildContext(s *gotypes.Scope, bc types.BuildContext) {
+	for _, name := range s.Names() {
+		switch ot := s.Lookup(name).(type) {
+		case *gotypes.TypeName:
+			tp, ok := r.refPkg.GetType(name)
+			if !ok {
+				continue
+			}
+			addBuildContext(tp, bc, r.objectConstraint(ot))
+			if named, ok := ot.Type().(*gotypes.Named); ok && !ot.IsAlias() {
+				r.annotateMethods(named, tp, bc)
+			}
+		case *gotypes.Func:
+			if ft, ok := r.refPkg.GetFunction(name); ok {
+				addBuildContext(ft, bc, r.objectConstraint(ot))
+			}
+		case *gotypes.Const, *gotypes.Var:
+			decl, ok := r.refPkg.Declarations[name]
+			if !ok {
+				continue
+			}
+			mergeBuild(&decl.Builds, &decl.BuildConstraint, bc, r.objectConstraint(ot))
+			r.refPkg.Declarations[name] = decl
+		}
+	}
+}
+
+func (r *rootPackage) annotateMethods(named *gotypes.Named, tp types.Type, bc types.BuildContext) {
+	var methods *[]types.Function
+	switch t := tp.(type) {
+	case *types.Struct:
+		methods = &t.Methods
+	case *types.Alias:
+		methods = &t.Methods
+	default:
+		return
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		index := -1
+		for j, m := range *methods {
+			if m.FuncName == named.Method(i).Name() {
+				index = j
+				break
+			}
+		}
+		if index == -1 {
+			// The method is declared only under given build context.
+			m, ok := r.parseMethod(r.refPkg, named, i, true)
+			if !ok {
+				continue
+			}
+			*methods = append(*methods, m)
+			index = len(*methods) - 1
+		}
+		m := &(*methods)[index]
+		mergeBuild(&m.Builds, &m.BuildConstraint, bc, r.objectConstraint(named.Method(i)))
+	}
+	sort.Slice(*methods, func(i, j int) bool { return (*methods)[i].FuncName < (*methods)[j].FuncName })
+}
+
+func addBuildContext(tp types.Type, bc types.BuildContext, expr string) {
+	switch t := tp.(type) {
+	case *types.Struct:
+		mergeBuild(&t.Builds, &t.BuildConstraint, bc, expr)
+	case *types.Interface:
+		mergeBuild(&t.Builds, &t.BuildConstraint, bc, expr)
+	case *types.Alias:
+		mergeBuild(&t.Builds, &t.BuildConstraint, bc, expr)
+	case *types.Function:
+		mergeBuild(&t.Builds, &t.BuildConstraint, bc, expr)
+	}
+}
+
+// mergeBuild adds the build context along with the build constraint under which the declaration exists.
+// The constraints of the declarations found in different files are combined with the OR operator, and the result
+// is empty if any of the files is not constrained.
+func mergeBuild(builds *types.BuildContexts, expr *string, bc types.BuildContext, fileExpr string) {
+	if builds.Contains(bc) {
+		return
+	}
+	if len(*builds) == 0 {
+		*expr = fileExpr
+	} else if *expr != fileExpr {
+		*expr = orConstraint(*expr, fileExpr)
+	}
+	*builds = append(*builds, bc)
+}
+
+// orConstraint combines two build constraint expressions with the OR operator. The terms already present
+// in the first expression are not added again.
+func orConstraint(x, y string) string {
+	if x == "" || y == "" {
+		return ""
+	}
+	xe, err := constraint.Parse("//go:build " + x)
+	if err != nil {
+		return ""
+	}
+	ye, err := constraint.Parse("//go:build " + y)
+	if err != nil {
+		return ""
+	}
+	terms := orTerms(xe, nil)
+	known := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		known[term.String()] = struct{}{}
+	}
+	result := xe
+	for _, term := range orTerms(ye, nil) {
+		if _, ok := known[term.String()]; ok {
+			continue
+		}
+		known[term.String()] = struct{}{}
+		result = &constraint.OrExpr{X: result, Y: term}
+	}
+	return result.String()
+}
+
+// orTerms gets the operands of the top level OR expressions.
+func orTerms(expr constraint.Expr, terms []constraint.Expr) []constraint.Expr {
+	if or, ok := expr.(*constraint.OrExpr); ok {
+		return orTerms(or.Y, orTerms(or.X, terms))
+	}
+	return append(terms, expr)
+}
+
+// objectConstraint gets the build constraint expression of the file declaring given object. The file header
+// constraint is combined with the one implied by the file name suffix i.e.: 'foo_linux.go'.
+func (r *rootPackage) objectConstraint(obj gotypes.Object) string {
+	if r.pkgPkg.Fset == nil || !obj.Pos().IsValid() {
+		return ""
+	}
+	fileName := r.pkgPkg.Fset.Position(obj.Pos()).Filename
+	if expr, ok := r.constraints[fileName]; ok {
+		return expr
+	}
+	expr := fileNameConstraint(fileName)
+	if header := r.fileHeader(fileName); header != nil {
+		if hc := fileConstraint(header, nil); hc != nil {
+			if expr == nil {
+				expr = hc
+			} else {
+				expr = &constraint.AndExpr{X: hc, Y: expr}
+			}
+		}
+	}
+	var result string
+	if expr != nil {
+		result = expr.String()
+	}
+	if r.constraints == nil {
+		r.constraints = map[string]string{}
+	}
+	r.constraints[fileName] = result
+	return result
+}
+
+// fileHeader gets the syntax of given package file. If the package syntax is not loaded, the file is parsed
+// up to its package clause.
+func (r *rootPackage) fileHeader(fileName string) *ast.File {
+	for _, file := range r.pkgPkg.Syntax {
+		if tf := r.pkgPkg.Fset.File(file.Pos()); tf != nil && tf.Name() == fileName {
+			return file
+		}
+	}
+	src, err := r.readSource(fileName)
+	if err != nil {
+		return nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), fileName, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+// fileNameConstraint gets the build constraint implied by the file name i.e.: 'linux && amd64'
+// for the 'foo_linux_amd64.go' file. The result is nil if the file name doesn't constrain the file.
+func fileNameConstraint(fileName string) constraint.Expr {
+	name := strings.TrimSuffix(filepath.Base(fileName), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+	l := strings.Split(name[i:], "_")
+	n := len(l)
+	switch {
+	case n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]]:
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: l[n-2]}, Y: &constraint.TagExpr{Tag: l[n-1]}}
+	case knownOS[l[n-1]] || knownArch[l[n-1]]:
+		return &constraint.TagExpr{Tag: l[n-1]}
+	}
+	return nil
+}
+
+// knownOS and knownArch are the operating systems and architectures recognized in the file name suffixes.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+		"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+		"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// packageCache is the on-disk cache of the parsed packages. Each package is stored under the key computed from
+// the content hashes of its files, the keys of its imports, the Go version and the load configuration.
+type packageCache struct {
+	dir     string
+	cfg     *LoadConfig
+	build   *types.BuildContext
+	overlay map[string][]byte
+	keys    map[*packages.Package]string
+	// pkgMap is the map of the loaded packages, which tells if the package was requested.
+	pkgMap *packageMap
+}
+
+func newPackageCache(cfg *LoadConfig, build *types.BuildContext, pkgMap *packageMap) (*packageCache, error) {
+	overlay, err := cfg.absOverlay()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(cfg.CacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &packageCache{dir: cfg.CacheDir, cfg: cfg, build: build, overlay: overlay, keys: map[*packages.Package]string{}, pkgMap: pkgMap}, nil
+}
+
+// key gets the cache key of given package. The second result is false if the package could not be cached
+// i.e. the package or any of its imports contains errors.
+func (c *packageCache) key(pkg *packages.Package) (string, bool) {
+	if key, ok := c.keys[pkg]; ok {
+		return key, key != ""
+	}
+	// The empty key marks the package as not cacheable, and prevents from the infinite recursion.
+	c.keys[pkg] = ""
+	if len(pkg.Errors) != 0 || pkg.Types == nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	writeField := func(values ...string) {
+		for _, v := range values {
+			io.WriteString(h, strconv.Quote(v))
+		}
+		io.WriteString(h, "\n")
+	}
+	writeField("gentools", strconv.Itoa(types.EncodingVersion), runtime.Version())
+	writeField(pkg.ID, pkg.PkgPath, pkg.Name)
+	writeField(c.cfg.BuildFlags...)
+	if c.build != nil {
+		writeField(c.build.String())
+	}
+	writeField(strconv.FormatBool(c.cfg.WithComments), strconv.FormatBool(c.cfg.Tests))
+	writeField(c.cfg.markersCacheKey()...)
+	writeField(c.cfg.filterCacheKey(c.pkgMap.isRequested(pkg.PkgPath))...)
+
+	files := pkg.CompiledGoFiles
+	if len(files) == 0 {
+		files = pkg.GoFiles
+	}
+	for _, fileName := range files {
+		content, ok := c.overlay[fileName]
+		if !ok {
+			var err error
+			if content, err = os.ReadFile(fileName); err != nil {
+				return "", false
+			}
+		}
+		sum := sha256.Sum256(content)
+		writeField(fileName, hex.EncodeToString(sum[:]))
+	}
+
+	paths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		key, ok := c.key(pkg.Imports[path])
+		if !ok {
+			return "", false
+		}
+		writeField(path, key)
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+	c.keys[pkg] = key
+	return key, true
+}
+
+func (c *packageCache) fileName(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// read gets the cached package. The types of other packages are resolved within given package map.
+func (c *packageCache) read(pkg *packages.Package, pkgMap types.PackageMap) (*types.Package, bool) {
+	key, ok := c.key(pkg)
+	if !ok {
+		return nil, false
+	}
+	f, err := os.Open(c.fileName(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	p, err := types.DecodePackage(f, pkgMap)
+	if err != nil {
+		return nil, false
+	}
+	return p, true
+}
+
+// write stores the parsed package in the cache. The file is written atomically, so that concurrent loaders
+// sharing the cache directory never read partially written packages.
+func (c *packageCache) write(pkg *packages.Package, p *types.Package) error {
+	key, ok := c.key(pkg)
+	if !ok {
+		return nil
+	}
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err = types.EncodePackage(f, p); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err = os.Rename(f.Name(), c.fileName(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// restoreCachedPackages restores the cached packages from given list. A package is restored only if all of its
+// imports are restored or already exists in the map, so that the references to their types could be resolved.
+// The function returns the packages that needs to be parsed.
+func (p *packageMap) restoreCachedPackages(c *packageCache, pkgList []*importedPackage) []*importedPackage {
+	listed := make(map[string]*importedPackage, len(pkgList))
+	for _, importedPkg := range pkgList {
+		listed[importedPkg.typesPkg.Path()] = importedPkg
+	}
+
+	restored := map[string]bool{}
+	var restore func(path string) bool
+	restore = func(path string) bool {
+		importedPkg, ok := listed[path]
+		if !ok {
+			_, ok = p.read(path)
+			return ok
+		}
+		if done, ok := restored[path]; ok {
+			return done
+		}
+		restored[path] = false
+		for _, imp := range importedPkg.pkgPkg.Imports {
+			if !restore(imp.PkgPath) {
+				return false
+			}
+		}
+		pkg, ok := c.read(importedPkg.pkgPkg, p.pkgMap)
+		if !ok {
+			return false
+		}
+		p.write(path, pkg)
+		restored[path] = true
+		return true
+	}
+
+	var toParse []*importedPackage
+	for _, importedPkg := range pkgList {
+		if !restore(importedPkg.typesPkg.Path()) {
+			toParse = append(toParse, importedPkg)
+		}
+	}
+	return toParse
+}
+
+// writeCachedPackages stores the parsed packages in the cache. The packages with errors are not stored.
+func (p *packageMap) writeCachedPackages(c *packageCache, parsed []*importedPackage) {
+	failed := map[string]struct{}{}
+	for _, d := range p.diagnostics {
+		if d.Severity == SeverityError {
+			failed[d.PkgPath] = struct{}{}
+		}
+	}
+	for _, importedPkg := range parsed {
+		path := importedPkg.typesPkg.Path()
+		if _, ok := failed[path]; ok {
+			continue
+		}
+		pkg, ok := p.read(path)
+		if !ok || pkg.IsLazy() {
+			continue
+		}
+		if err := c.write(importedPkg.pkgPkg, pkg); err != nil {
+			p.report(Diagnostic{
+				PkgPath:  path,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("writing package cache failed: %v", err),
+			})
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// ErrPackageErrors is the error returned by the loader when some of the loaded packages contains errors,
+// and the LoadConfig.BestEffort mode is not enabled. The details are provided in the resulting Diagnostics.
+var ErrPackageErrors = errors.New("loaded packages contains errors")
+
+// Severity is the severity level of the load diagnostic.
+type Severity int
+
+// Enumerated severity levels.
+const (
+	SeverityWarning Severity = iota + 1
+	SeverityError
+)
+
+// String implements fmt.Stringer interface.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a single problem found while loading or parsing the packages.
+type Diagnostic struct {
+	// PkgPath is the path of the package where the problem occurred.
+	PkgPath string
+	// Name is the name of the declaration related to the problem. It might be empty for the package wide problems.
+	Name     string
+	Pos      types.Position
+	Severity Severity
+	Message  string
+}
+
+// String implements fmt.Stringer interface.
+func (d Diagnostic) String() string {
+	sb := strings.Builder{}
+	if d.Pos.IsValid() {
+		sb.WriteString(d.Pos.String())
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Severity.String())
+	sb.WriteString(": ")
+	if d.PkgPath != "" {
+		sb.WriteString(d.PkgPath)
+		if d.Name != "" {
+			sb.WriteRune('.')
+			sb.WriteString(d.Name)
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Message)
+	return sb.String()
+}
+
+// Diagnostics is the list of load diagnostics.
+type Diagnostics []Diagnostic
+
+// HasErrors checks if the diagnostics contains any error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors gets only the diagnostics with the SeverityError level.
+func (d Diagnostics) Errors() Diagnostics {
+	var errs Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			errs = append(errs, diagnostic)
+		}
+	}
+	return errs
+}
+
+// String implements fmt.Stringer interface. Each diagnostic is written in a separate line.
+func (d Diagnostics) String() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sortDiagnostics sorts the diagnostics by their package path, file and position,
+// so that the result is deterministic regardless of the concurrent parsing.
+func sortDiagnostics(d Diagnostics) {
+	sort.SliceStable(d, func(i, j int) bool {
+		if d[i].PkgPath != d[j].PkgPath {
+			return d[i].PkgPath < d[j].PkgPath
+		}
+		if d[i].Pos.Filename != d[j].Pos.Filename {
+			return d[i].Pos.Filename < d[j].Pos.Filename
+		}
+		if d[i].Pos.Line != d[j].Pos.Line {
+			return d[i].Pos.Line < d[j].Pos.Line
+		}
+		return d[i].Pos.Column < d[j].Pos.Column
+	})
+}
+
+// report adds the diagnostic to the package map. The result is false if the diagnostics of the package map
+// were already returned by the load i.e. for the types mapped lazily afterwards.
+func (p *packageMap) report(d Diagnostic) bool {
+	p.Lock()
+	defer p.Unlock()
+	if p.finished {
+		return false
+	}
+	p.diagnostics = append(p.diagnostics, d)
+	if d.Severity == SeverityError {
+		p.errors++
+	}
+	return true
+}
+
+// errorCount gets the number of the error diagnostics reported by the parser.
+func (p *packageMap) errorCount() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.errors
+}
+
+// finishDiagnostics marks the diagnostics of the package map as returned by the load.
+func (p *packageMap) finishDiagnostics() {
+	p.Lock()
+	defer p.Unlock()
+	p.finished = true
+}
+
+// packageErrors gets the diagnostics of the errors of given packages and all of their dependencies.
+func packageErrors(pkgs []*packages.Package) Diagnostics {
+	var d Diagnostics
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			d = append(d, Diagnostic{
+				PkgPath:  pkg.PkgPath,
+				Pos:      parseErrorPosition(err.Pos),
+				Severity: SeverityError,
+				Message:  err.Msg,
+			})
+		}
+	})
+	return d
+}
+
+// parseErrorPosition parses the 'file:line:column' position of the packages.Error.
+func parseErrorPosition(pos string) types.Position {
+	var p types.Position
+	// The position might be in a form of 'file', 'file:line' or 'file:line:column'.
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndexByte(pos, ':')
+		if idx == -1 {
+			break
+		}
+		n, err := strconv.Atoi(pos[idx+1:])
+		if err != nil {
+			break
+		}
+		p.Column, p.Line = p.Line, n
+		pos = pos[:idx]
+	}
+	if p.Line == 0 {
+		return types.Position{}
+	}
+	p.Filename = pos
+	return p
+}
+
+// warnf reports the warning diagnostic related to given declaration.
+func (r *rootPackage) warnf(name string, pos token.Pos, format string, args ...interface{}) {
+	r.reportf(SeverityWarning, name, pos, format, args...)
+}
+
+// errorf reports the error diagnostic related to given declaration.
+func (r *rootPackage) errorf(name string, pos token.Pos, format string, args ...interface{}) {
+	r.reportf(SeverityError, name, pos, format, args...)
+}
+
+func (r *rootPackage) reportf(severity Severity, name string, pos token.Pos, format string, args ...interface{}) {
+	d := Diagnostic{
+		PkgPath:  r.typesPkg.Path(),
+		Name:     name,
+		Pos:      r.tokenPosition(pos),
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if !r.pkgMap.report(d) {
+		r.loadConfig.lateDiagnostic(d)
+	}
+}
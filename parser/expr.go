@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
-	"log"
 	"strconv"
 	"strings"
 
@@ -25,7 +24,7 @@ func (r *rootPackage) extractAliasExpr(f *ast.File, expr ast.Expr) (types.Type,
 	case *ast.SelectorExpr:
 		i, ok := x.X.(*ast.Ident)
 		if !ok {
-			panic("selector expression is not an ident")
+			return nil, fmt.Errorf("selector expression is not an ident: %T", x.X)
 		}
 		for _, im := range f.Imports {
 			pkgPath := strings.Trim(im.Path.Value, "\"")
@@ -52,6 +51,12 @@ func (r *rootPackage) extractAliasExpr(f *ast.File, expr ast.Expr) (types.Type,
 
 			root, ok := r.rootPackages[pkg.Types]
 			if !ok {
+				// The lazily mapped dependency package doesn't have the syntax loaded.
+				if lp, ok := r.pkgMap.read(pkg.PkgPath); ok {
+					if tp, ok := lp.GetType(x.Sel.Name); ok {
+						return tp, nil
+					}
+				}
 				return nil, fmt.Errorf("root package: %s not found", pkg.PkgPath)
 			}
 			// Find matching file for the selector definition.
@@ -74,9 +79,7 @@ func (r *rootPackage) extractAliasExpr(f *ast.File, expr ast.Expr) (types.Type,
 		}
 		tp, ok := r.refPkg.GetType(x.Name)
 		if !ok {
-			if r.loadConfig.Verbose {
-				log.Printf("ident: '%s' not found in the package: '%s'", x.Name, r.refPkg.Path)
-			}
+			r.loadConfig.log(LogDebug, "ident not found in the package", "package", r.refPkg.Path, "ident", x.Name)
 			return nil, errIdentNotFound
 		}
 		return tp, nil
@@ -169,7 +172,7 @@ func (r *rootPackage) extractStructExpr(file *ast.File, expr ast.Expr) (*ast.Str
 	case *ast.SelectorExpr:
 		i, ok := x.X.(*ast.Ident)
 		if !ok {
-			panic("selector expression is not an ident")
+			return nil, false
 		}
 
 		for _, im := range file.Imports {
@@ -237,7 +240,7 @@ func (r *rootPackage) extractInterfaceExpr(file *ast.File, expr ast.Expr) (*ast.
 	case *ast.SelectorExpr:
 		i, ok := x.X.(*ast.Ident)
 		if !ok {
-			panic("selector expression is not an ident")
+			return nil, false
 		}
 		for _, im := range file.Imports {
 			pkgPath := strings.Trim(im.Path.Value, "\"")
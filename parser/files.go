@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// generatedComment matches the comment of the generated files i.e.: '// Code generated by stringer; DO NOT EDIT.'.
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// parseFiles sets the package doc comment and the package files with their build constraints and directives.
+// The doc comments defined in multiple files are concatenated. The files already parsed under another build context
+// are not added again.
+func (r *rootPackage) parseFiles(p *types.Package) {
+	known := make(map[string]struct{}, len(p.Files))
+	for _, f := range p.Files {
+		known[f.Path] = struct{}{}
+	}
+	var docs []string
+	for _, file := range r.pkgPkg.Syntax {
+		tf := r.pkgPkg.Fset.File(file.Pos())
+		if tf == nil {
+			continue
+		}
+		if _, ok := known[tf.Name()]; ok {
+			continue
+		}
+		known[tf.Name()] = struct{}{}
+		if doc := commentText(file.Doc); doc != "" {
+			docs = append(docs, doc)
+		}
+		p.Files = append(p.Files, r.parseFile(tf.Name(), file))
+	}
+	if p.Comment == "" {
+		p.Comment = strings.Join(docs, "\n")
+	}
+	sort.Slice(p.Files, func(i, j int) bool { return p.Files[i].Path < p.Files[j].Path })
+}
+
+// parseFile gets the file with its build constraint, generated state and directives.
+func (r *rootPackage) parseFile(fileName string, file *ast.File) *types.File {
+	f := &types.File{Path: fileName}
+	expr := fileConstraint(file, func(c *ast.Comment, err error) {
+		r.warnf("", c.Slash, "invalid build constraint: %v", err)
+	})
+	if expr != nil {
+		f.BuildConstraint = expr.String()
+	}
+	for _, cg := range file.Comments {
+		// The generated comment is expected before the package clause.
+		header := cg.Pos() < file.Package
+		for _, c := range cg.List {
+			if header && generatedComment.MatchString(c.Text) {
+				f.Generated = true
+			}
+			if d, ok := parseDirective(c.Text); ok {
+				d.Pos = r.tokenPosition(c.Slash)
+				f.Directives = append(f.Directives, d)
+			}
+		}
+	}
+	return f
+}
+
+// fileConstraint gets the build constraint of the file header. The '//go:build' line is preferred over
+// the legacy '// +build' lines, which are combined with the AND operator. The result is nil if the file is not
+// constrained. The invalid '//go:build' line is reported to given function, if defined.
+func fileConstraint(file *ast.File, invalid func(c *ast.Comment, err error)) constraint.Expr {
+	var goBuild, plusBuild constraint.Expr
+	for _, cg := range file.Comments {
+		// The build constraints are expected before the package clause.
+		if cg.Pos() >= file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				expr, err := constraint.Parse(c.Text)
+				if err != nil {
+					if invalid != nil {
+						invalid(c, err)
+					}
+					continue
+				}
+				goBuild = expr
+			case constraint.IsPlusBuild(c.Text):
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					if plusBuild == nil {
+						plusBuild = expr
+					} else {
+						plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+					}
+				}
+			}
+		}
+	}
+	if goBuild != nil {
+		return goBuild
+	}
+	return plusBuild
+}
+
+// readSource reads the content of given file. The overlay content is preferred over the file on disk.
+func (r *rootPackage) readSource(fileName string) ([]byte, error) {
+	return r.pkgMap.overlay.ReadFile(fileName)
+}
+
+// parseDirective parses the compiler directive comment i.e.: '//go:generate stringer -type=Kind'.
+// The second result is false if the comment is not a directive.
+func parseDirective(comment string) (types.Directive, bool) {
+	if !strings.HasPrefix(comment, "//go:") {
+		return types.Directive{}, false
+	}
+	name, text := comment[2:], ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, text = name[:i], strings.TrimSpace(name[i:])
+	}
+	if name == "go:" {
+		return types.Directive{}, false
+	}
+	return types.Directive{Name: name, Args: directiveArgs(text), Text: text}, true
+}
+
+// directiveArgs splits the directive arguments by the white spaces. The double-quoted and back-quoted arguments
+// might contain the spaces, and are unquoted.
+func directiveArgs(text string) []string {
+	var args []string
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimLeftFunc(text, unicode.IsSpace) {
+		if text[0] == '"' || text[0] == '`' {
+			if quoted, err := strconv.QuotedPrefix(text); err == nil {
+				if arg, err := strconv.Unquote(quoted); err == nil {
+					args = append(args, arg)
+					text = text[len(quoted):]
+					continue
+				}
+			}
+		}
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end < 0 {
+			end = len(text)
+		}
+		args = append(args, text[:end])
+		text = text[end:]
+	}
+	return args
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"go/token"
+	gotypes "go/types"
+	"regexp"
+	"strings"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// excludesPackage checks if the dependency package with given import path is filtered out by the Include
+// and Exclude patterns.
+func (c *LoadConfig) excludesPackage(path string) bool {
+	if len(c.Include) != 0 && !matchPathPatterns(c.Include, path) {
+		return true
+	}
+	return matchPathPatterns(c.Exclude, path)
+}
+
+// filterCacheKey gets the fields of the filter options, that changes the parsed package. The requested packages
+// are mapped in full even in the ExportedOnly mode.
+func (c *LoadConfig) filterCacheKey(requested bool) []string {
+	var fields []string
+	if c.ExportedOnly && !requested {
+		fields = append(fields, "exported")
+	}
+	if len(c.Include) != 0 || len(c.Exclude) != 0 {
+		fields = append(fields, "include")
+		fields = append(fields, c.Include...)
+		fields = append(fields, "exclude")
+		fields = append(fields, c.Exclude...)
+	}
+	return fields
+}
+
+// matchPathPatterns checks if the import path matches any of given patterns.
+func matchPathPatterns(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchPathPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathPattern checks if the import path matches given pattern. The '...' wildcard matches any string
+// including the slashes, and the '*' wildcard matches any string within a single path element. Like the go list
+// patterns, the pattern ending with '/...' matches also the path without it i.e.: 'net/...' matches 'net'.
+func matchPathPattern(pattern, path string) bool {
+	if !strings.Contains(pattern, "*") && !strings.Contains(pattern, "...") {
+		return pattern == path
+	}
+	if strings.HasSuffix(pattern, "/...") && matchPathPattern(strings.TrimSuffix(pattern, "/..."), path) {
+		return true
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+	expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+	matched, err := regexp.MatchString("^"+expr+"$", path)
+	return err == nil && matched
+}
+
+// setRequested marks the packages with given import paths as requested, so that these are mapped in full
+// regardless of the ExportedOnly and the Include and Exclude filters.
+func (p *packageMap) setRequested(paths ...string) {
+	p.Lock()
+	defer p.Unlock()
+	if p.requested == nil {
+		p.requested = map[string]struct{}{}
+	}
+	for _, path := range paths {
+		p.requested[path] = struct{}{}
+	}
+}
+
+// isRequested checks if the package with given import path was requested to load.
+func (p *packageMap) isRequested(path string) bool {
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.requested[path]
+	return ok
+}
+
+// excludesDependency checks if the package with given import path is not requested, and is excluded by the filters.
+func (p *packageMap) excludesDependency(cfg *LoadConfig, path string) bool {
+	return (len(cfg.Include) != 0 || len(cfg.Exclude) != 0) && !p.isRequested(path) && cfg.excludesPackage(path)
+}
+
+// mapsName checks if the declaration or struct field with given name of the package with given import path is mapped.
+// In the ExportedOnly mode only the exported names of the dependency packages are mapped.
+func (p *packageMap) mapsName(cfg *LoadConfig, pkgPath, name string) bool {
+	return !cfg.ExportedOnly || token.IsExported(name) || p.isRequested(pkgPath)
+}
+
+// opaqueType gets the opaque reference to the named type that is not mapped i.e. the type of the excluded package.
+func (r *rootPackage) opaqueType(named *gotypes.Named) (types.Type, bool) {
+	obj := named.Obj()
+	o := &types.Opaque{
+		PkgPath:        obj.Pkg().Path(),
+		Identifier:     obj.Pkg().Name(),
+		TypeName:       obj.Name(),
+		UnderlyingKind: underlyingKind(named.Underlying()),
+	}
+	for i := 0; i < named.TypeArgs().Len(); i++ {
+		at, ok := r.dereferenceType(r.refPkg, named.TypeArgs().At(i))
+		if !ok {
+			return nil, false
+		}
+		o.TypeArgs = append(o.TypeArgs, at)
+	}
+	return o, true
+}
+
+// underlyingKind gets the kind of the underlying type of the opaque type.
+func underlyingKind(tp gotypes.Type) types.Kind {
+	switch t := tp.(type) {
+	case *gotypes.Basic:
+		if t.Kind() == gotypes.UnsafePointer {
+			return types.KindUnsafePointer
+		}
+		if bt, ok := types.GetBuiltInType(t.Name()); ok {
+			return bt.Kind()
+		}
+		return types.Invalid
+	case *gotypes.Struct:
+		return types.KindStruct
+	case *gotypes.Interface:
+		return types.KindInterface
+	case *gotypes.Pointer:
+		return types.KindPtr
+	case *gotypes.Slice:
+		return types.KindSlice
+	case *gotypes.Array:
+		return types.KindArray
+	case *gotypes.Map:
+		return types.KindMap
+	case *gotypes.Chan:
+		return types.KindChan
+	case *gotypes.Signature:
+		return types.KindFunc
+	default:
+		return types.Invalid
+	}
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"context"
+	"go/token"
+	gotypes "go/types"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// lazyPackage is the types.TypeResolver that maps the dependency package types on their first access.
+// Only the types that are referenced are mapped, up to the LoadConfig.LazyTypesLimit.
+type lazyPackage struct {
+	sync.Mutex
+	root *rootPackage
+}
+
+// ResolveType implements types.TypeResolver interface. The types are mapped under the lock, thus the concurrent
+// callers wait until the type is finished. The mapped types are set in the package only once these are finished.
+func (l *lazyPackage) ResolveType(name string) (types.Type, bool) {
+	l.Lock()
+	defer l.Unlock()
+	tp, ok := l.root.resolveLazyType(name)
+	l.root.publishTypes()
+	return tp, ok
+}
+
+// packageType gets the type with given name from given package. The types of the lazy package mapped by the root
+// are resolved directly, as its lock is already held, so that the recursive references get the types in progress.
+func (r *rootPackage) packageType(p *types.Package, name string) (types.Type, bool) {
+	if r.lazy && p == r.refPkg {
+		return r.resolveLazyType(name)
+	}
+	return p.GetType(name)
+}
+
+// publishTypes sets the finished types of the lazy package in the package.
+func (r *rootPackage) publishTypes() {
+	for _, name := range r.unpublished {
+		r.refPkg.SetNamedType(name, r.typesInProgress[name])
+	}
+	r.unpublished = nil
+}
+
+// resolveLazyType maps the lazy package type with given name. It is expected to be called under the lazy package lock.
+func (r *rootPackage) resolveLazyType(name string) (types.Type, bool) {
+	if tp, ok := r.typesInProgress[name]; ok {
+		return tp, true
+	}
+	obj := r.typesPkg.Scope().Lookup(name)
+	switch obj.(type) {
+	case *gotypes.TypeName, *gotypes.Func:
+	default:
+		return nil, false
+	}
+	if !r.pkgMap.mapsName(r.loadConfig, r.typesPkg.Path(), name) {
+		return nil, false
+	}
+	if !r.pkgMap.reserveLazyType(r.loadConfig.LazyTypesLimit) {
+		r.warnf(name, obj.Pos(), "type not mapped: lazy types limit %d reached", r.loadConfig.LazyTypesLimit)
+		return nil, false
+	}
+	// The type is scaffolded before it is finished, so that the recursive references could resolve it.
+	r.scaffoldObject(name, obj)
+	tp, ok := r.typesInProgress[name]
+	if !ok {
+		return nil, false
+	}
+
+	if alias, ok := tp.(*types.Alias); ok && alias.TypeAlias {
+		r.finishTypeAlias(obj, alias)
+		return tp, true
+	}
+	switch t := unalias(obj.Type()).(type) {
+	case *gotypes.Named:
+		if !r.finishNamedType(t, tp) {
+			r.warnf(name, obj.Pos(), "type not mapped")
+		}
+	case *gotypes.Signature:
+		if !r.finishNamedFunc(t, tp) {
+			r.warnf(name, obj.Pos(), "function not mapped")
+		}
+	}
+	return tp, true
+}
+
+// lazyPackage gets or creates the lazily mapped package for given dependency.
+func (p *packageMap) lazyPackage(cfg *LoadConfig, fset *token.FileSet, typesPkg *gotypes.Package) *types.Package {
+	p.Lock()
+	defer p.Unlock()
+	if pkg, ok := p.pkgMap[typesPkg.Path()]; ok {
+		return pkg
+	}
+	pkg := types.NewPackage(typesPkg.Path(), typesPkg.Name())
+	pkg.Imports = mergeImportPaths(nil, typesPkg)
+	r := &rootPackage{
+		// The types are resolved after the load is finished, thus it cannot be affected by the load cancellation.
+		ctx:             context.Background(),
+		pkgPkg:          &packages.Package{ID: typesPkg.Path(), Name: typesPkg.Name(), PkgPath: typesPkg.Path(), Types: typesPkg, Fset: fset},
+		typesPkg:        typesPkg,
+		refPkg:          pkg,
+		pkgMap:          p,
+		loadConfig:      cfg,
+		rootPackages:    map[*gotypes.Package]*rootPackage{},
+		typesInProgress: map[string]types.Type{},
+		mappedAliases:   map[string]struct{}{},
+		namedAliases:    map[string]*gotypes.Named{},
+		merged:          map[string]struct{}{},
+		lazy:            true,
+	}
+	pkg.SetTypeResolver(&lazyPackage{root: r})
+	p.pkgMap[typesPkg.Path()] = pkg
+	return pkg
+}
+
+// registerLazyImports registers the lazily mapped packages for all the imports of given package.
+func (p *packageMap) registerLazyImports(cfg *LoadConfig, fset *token.FileSet, typesPkg *gotypes.Package, visited map[string]struct{}, excluded func(path string) bool) {
+	for _, imp := range typesPkg.Imports() {
+		if _, ok := visited[imp.Path()]; ok || excluded(imp.Path()) {
+			continue
+		}
+		visited[imp.Path()] = struct{}{}
+		p.lazyPackage(cfg, fset, imp)
+		p.registerLazyImports(cfg, fset, imp, visited, excluded)
+	}
+}
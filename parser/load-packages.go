@@ -1,20 +1,19 @@
 package parser
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/token"
 	gotypes "go/types"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
-	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 
 	"github.com/kucjac/gentools/types"
@@ -22,66 +21,154 @@ import (
 
 // LoadConfig contains configuration used while loading packages.
 type LoadConfig struct {
-	// Paths could be absolute or relative path to given directory.
+	// Paths could be absolute or relative path to given directory. The paths might also be the go list patterns
+	// i.e.: './...' or 'std'. The directories with no go files are not matched.
 	Paths []string
-	// PkgNames should be full pkg name i.e.: 'golang.org/x/mod/modfile'
+	// PkgNames should be full pkg name i.e.: 'golang.org/x/mod/modfile', or the go list pattern
+	// i.e.: 'golang.org/x/mod/...'. The import paths of the matched packages are provided by the LoadStats.
 	PkgNames []string
 	// BuildFlags are the flags used by the ast.
 	BuildFlags []string
-	// Verbose sets the loader in verbose mode.
+	// Verbose sets the loader in verbose mode, where the debug entries are written to the Logger.
 	Verbose bool
 	// WithComments
 	WithComments bool
+	// BestEffort enables partial loading of the packages that contains errors. The errors are reported
+	// in the resulting Diagnostics, and the declarations that could not be parsed are skipped.
+	// By default, any package error, including the error diagnostics of the parser, results in the ErrPackageErrors.
+	BestEffort bool
+	// Overlay maps the file paths to their in-memory contents. The overlay files are used instead of the files
+	// on disk, and might define the files (or whole packages) that doesn't exist on disk yet.
+	// Relative file paths are resolved against the Dir.
+	Overlay map[string][]byte
+	// BuildContexts are the build configurations under which the packages are loaded.
+	// If defined, the packages are loaded for each build context and merged together, so that the result contains
+	// the union of the declarations. The types, functions, methods and declarations are annotated with the build
+	// contexts under which they exist, along with the build constraint of the files declaring them.
+	// The declarations found in multiple build contexts are taken from the first one that contains them.
+	// The packages with the same files under multiple build contexts (i.e. most of the dependencies) are parsed
+	// only once, and annotated with the following build contexts.
+	BuildContexts []types.BuildContext
+	// Tests enables loading the package test files along with the external test packages i.e.: 'foo_test'.
+	// The packages loaded with their tests have the ForTest field defined, and contain the testable Examples.
+	Tests bool
+	// LazyDependencies enables the mode where only the requested packages are parsed in full.
+	// The dependency packages are loaded from the compiled export data, and their types are mapped lazily
+	// on the first access i.e. by the Package.GetType or PackageMap.TypeOf. The dependency packages doesn't contain
+	// the comments nor the declarations (variables and constants). The mapped types are kept as long as the packages
+	// are in use, thus the memory grows with the number of the dependency types in use, up to the LazyTypesLimit.
+	LazyDependencies bool
+	// LazyTypesLimit is the maximum number of the dependency types mapped lazily in the LazyDependencies mode.
+	// The types beyond the limit are not mapped, and are reported with the warning diagnostics.
+	// If zero, the number of the mapped types is not limited.
+	LazyTypesLimit int
+	// LazyDiagnostics receives the diagnostics reported by the lazily mapped dependencies after the load
+	// has returned. If not defined, these are written to the Logger.
+	LazyDiagnostics func(Diagnostic)
+	// CacheDir is the directory of the persistent packages cache. If defined, the parsed packages are stored
+	// in the cache, and the unchanged ones are restored on the following loads instead of being parsed again.
+	// The packages are keyed by the content hashes of their files and imports, the Go version and the build
+	// configuration. The cache is not used for the LazyDependencies mode, nor for merging multiple BuildContexts.
+	CacheDir string
+	// Logger is the logger of the loader. Nothing is written to the standard output nor error streams,
+	// thus the loader logs are not available unless the Logger is defined.
+	Logger Logger
+	// Progress is the callback reporting the started and finished load phases and packages.
+	Progress func(Progress)
+	// Concurrency is the maximum number of the packages parsed concurrently. The packages are parsed after their
+	// imports are finished. By default, the limit is the number of CPUs usable by the process.
+	Concurrency int
+	// Stats if defined is set to the statistics of the load.
+	Stats *LoadStats
+	// Dir is the working directory of the loader. The relative Paths, patterns and Overlay files are resolved
+	// against it, and the go command is run within it. By default, the current working directory is used.
+	Dir string
+	// Env is the environment of the loader i.e.: GOFLAGS, GOOS, GOARCH, CGO_ENABLED or GOPACKAGESDRIVER.
+	// It is used by the go command (or the packages driver), and to resolve the import paths of the Paths directories.
+	// If nil, the current process environment is used.
+	Env []string
+	// Driver if defined is used instead of the packages.Load function to load the packages i.e. in order to use
+	// custom build system or to provide the packages in tests. The config provided to the driver has the Dir
+	// and Env already set.
+	Driver func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error)
+	// Markers is the schema of the known comment markers i.e.: '// +gentools:table=users'. If defined, the comments
+	// and markers are parsed even if the WithComments is disabled. The markers within the namespaces of the schema
+	// are validated, and the unknown or malformed ones are reported as warnings in the Diagnostics. The markers
+	// of other namespaces are parsed without the validation.
+	Markers *types.MarkerSchema
+	// ExportedOnly enables the mode where only the exported types, functions, struct fields and declarations
+	// of the dependency packages are mapped, whereas the requested packages are mapped in full. The methods
+	// of the mapped types are always mapped, as these define the method sets i.e. of the sealed interfaces.
+	// The references to the unexported types of the dependencies are replaced with the types.Opaque references.
+	ExportedOnly bool
+	// Include are the import path patterns of the dependency packages to parse i.e.: 'github.com/my/...'.
+	// If defined, only the matching dependencies are parsed. The '...' wildcard matches any string, whereas
+	// the '*' wildcard matches any string within a single path element.
+	Include []string
+	// Exclude are the import path patterns of the dependency packages, that are not parsed i.e.: '.../internal/...'.
+	// The requested packages are always parsed. The references to the types of the excluded packages are replaced
+	// with the types.Opaque references.
+	Exclude []string
 }
 
 // LoadPackages parses Golang packages using AST.
-func LoadPackages(cfg LoadConfig) (types.PackageMap, error) {
-	pkgNames, err := getPackageNames(&cfg)
-	if err != nil {
-		return nil, err
-	}
+// The problems found while loading and parsing the packages are returned as the Diagnostics.
+// If the packages contains errors and the BestEffort mode is disabled the function returns ErrPackageErrors.
+func LoadPackages(cfg LoadConfig) (types.PackageMap, Diagnostics, error) {
+	return LoadPackagesContext(context.Background(), cfg)
+}
 
+// LoadPackagesContext parses Golang packages using AST. The loading and parsing is stopped when given context
+// is done, in which case the function returns the context error.
+func LoadPackagesContext(ctx context.Context, cfg LoadConfig) (types.PackageMap, Diagnostics, error) {
 	p := &packageMap{pkgMap: types.PackageMap{}}
-	pkgs, err := p.loadPackages(&cfg, pkgNames...)
+	pkgNames, err := p.packageNames(ctx, &cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if len(pkgs) == 0 {
-		return nil, errors.New("no packages found")
+	if err = p.loadAndParse(ctx, &cfg, pkgNames...); err != nil {
+		return nil, p.diagnostics, err
 	}
-	p.parsePackages(&cfg, pkgs...)
-	return p.pkgMap, nil
+	sortDiagnostics(p.diagnostics)
+	return p.pkgMap, p.diagnostics, nil
 }
 
 // UpdatePackages updates the packages in the given PackageMap.
 // The function would get and parse only packages that doesn't currently exists in given map.
-func UpdatePackages(p types.PackageMap, cfg LoadConfig) error {
-	pkgNames, err := getPackageNames(&cfg)
+func UpdatePackages(p types.PackageMap, cfg LoadConfig) (Diagnostics, error) {
+	return UpdatePackagesContext(context.Background(), p, cfg)
+}
+
+// UpdatePackagesContext updates the packages in the given PackageMap. The loading and parsing is stopped
+// when given context is done, in which case the function returns the context error, and the packages map
+// is left unchanged.
+func UpdatePackagesContext(ctx context.Context, p types.PackageMap, cfg LoadConfig) (Diagnostics, error) {
+	pm := &packageMap{pkgMap: p}
+	pkgNames, err := pm.packageNames(ctx, &cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	pm := &packageMap{pkgMap: p}
 	pkgNames = pm.resolveLoadedPackages(pkgNames)
 	switch len(pkgNames) {
 	case 0:
-		if cfg.Verbose {
-			fmt.Println("all packages from the input already loaded")
-		}
+		cfg.log(LogInfo, "all packages from the input already loaded")
+		pm.resetStats()
+		pm.finishStats(&cfg)
 	default:
-		pkgs, err := pm.loadPackages(&cfg, pkgNames...)
-		if err != nil {
-			return err
-		}
-		if len(pkgs) == 0 {
-			return errors.New("no packages found")
+		if err = pm.loadAndParse(ctx, &cfg, pkgNames...); err != nil {
+			return pm.diagnostics, err
 		}
-		pm.parsePackages(&cfg, pkgs...)
 	}
-	return nil
+	sortDiagnostics(pm.diagnostics)
+	return pm.diagnostics, nil
 }
 
 // PackageNameOfDir get package import path via dir
 func PackageNameOfDir(srcDir string) (string, error) {
+	return packageNameOfDir(srcDir, os.Getenv)
+}
+
+func packageNameOfDir(srcDir string, getenv func(string) string) (string, error) {
 	files, err := ioutil.ReadDir(srcDir)
 	if err != nil {
 		return "", err
@@ -98,53 +185,193 @@ func PackageNameOfDir(srcDir string) (string, error) {
 		return "", fmt.Errorf("go source file not found %s", srcDir)
 	}
 
-	packageImport, err := parsePackageImport(srcDir)
+	packageImport, err := parsePackageImport(srcDir, getenv)
 	if err != nil {
 		return "", err
 	}
 	return packageImport, nil
 }
 
-func (p *packageMap) loadPackages(cfg *LoadConfig, pkgNames ...string) ([]*packages.Package, error) {
-	now := time.Now()
-	mode := packages.NeedName | packages.NeedDeps | packages.NeedImports | packages.NeedTypes
-	if cfg.WithComments {
+// loadAndParse loads and parses the packages with given names. If the config has multiple build contexts defined,
+// the packages are loaded for each of them and merged together.
+func (p *packageMap) loadAndParse(ctx context.Context, cfg *LoadConfig, pkgNames ...string) error {
+	p.resetStats()
+	defer p.finishStats(cfg)
+	// The diagnostics reported after the load are not a part of its result.
+	defer p.finishDiagnostics()
+	overlay, err := cfg.absOverlay()
+	if err != nil {
+		return err
+	}
+	p.overlay = types.NewOverlay(overlay)
+	if len(cfg.BuildContexts) == 0 {
+		pkgs, err := p.loadPackages(ctx, cfg, nil, pkgNames...)
+		if err != nil {
+			return err
+		}
+		if len(pkgs) == 0 {
+			return errors.New("no packages found")
+		}
+		return p.parsePackages(ctx, cfg, pkgs...)
+	}
+
+	p.fileSets = map[string]struct{}{}
+	defer func() {
+		p.build, p.merge, p.fileSets = nil, false, nil
+	}()
+	for i := range cfg.BuildContexts {
+		bc := &cfg.BuildContexts[i]
+		pkgs, err := p.loadPackages(ctx, cfg, bc, pkgNames...)
+		if err != nil {
+			return err
+		}
+		if len(pkgs) == 0 {
+			return fmt.Errorf("no packages found for the build context: %s", bc)
+		}
+		// The packages parsed under previous build contexts are merged with the new ones.
+		p.build, p.merge = bc, i > 0
+		if err = p.parsePackages(ctx, cfg, pkgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *packageMap) loadPackages(ctx context.Context, cfg *LoadConfig, bc *types.BuildContext, pkgNames ...string) ([]*packages.Package, error) {
+	mode := packages.NeedName | packages.NeedImports | packages.NeedTypes | packages.NeedModule | packages.NeedFiles
+	if !cfg.LazyDependencies {
+		mode |= packages.NeedDeps
+	}
+	if cfg.parsesComments() || cfg.Tests {
+		// The examples are parsed from the test files syntax.
 		mode |= packages.NeedSyntax
 	}
+	if cfg.CacheDir != "" {
+		// The cache keys are computed from the package files content.
+		mode |= packages.NeedCompiledGoFiles
+	}
+	overlay, err := cfg.absOverlay()
+	if err != nil {
+		return nil, err
+	}
 	pkgCfg := &packages.Config{
+		Context:    ctx,
 		Mode:       mode,
 		BuildFlags: cfg.BuildFlags,
+		Overlay:    overlay,
+		Tests:      cfg.Tests,
+	}
+	if bc != nil {
+		pkgCfg.Env = cfg.environ()
+		if bc.GOOS != "" {
+			pkgCfg.Env = append(pkgCfg.Env, "GOOS="+bc.GOOS)
+		}
+		if bc.GOARCH != "" {
+			pkgCfg.Env = append(pkgCfg.Env, "GOARCH="+bc.GOARCH)
+		}
+		if len(bc.Tags) != 0 {
+			pkgCfg.BuildFlags = append(append([]string{}, cfg.BuildFlags...), "-tags="+strings.Join(bc.Tags, ","))
+		}
 	}
 
-	pkgs, err := packages.Load(pkgCfg, pkgNames...)
+	var pkgs []*packages.Package
+	elapsed := p.runPhase(cfg, PhaseLoad, func() {
+		pkgs, err = cfg.load(pkgCfg, pkgNames...)
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
-	if packages.PrintErrors(pkgs) > 1 {
-		return nil, errors.New("error while loading import packages")
-	}
-	if cfg.Verbose {
-		log.Printf("AST packages loaded in: %s\n", time.Since(now))
+	if errs := packageErrors(pkgs); len(errs) != 0 {
+		p.diagnostics = append(p.diagnostics, errs...)
+		if !cfg.BestEffort {
+			return nil, ErrPackageErrors
+		}
 	}
+	cfg.log(LogInfo, "AST packages loaded", "packages", len(pkgs), "elapsed", elapsed)
 	return pkgs, nil
 }
 
-func getPackageNames(cfg *LoadConfig) (pkgNames []string, err error) {
-	for _, pkgPath := range cfg.Paths {
-		if !filepath.IsAbs(pkgPath) {
-			pkgPath, err = filepath.Abs(pkgPath)
-			if err != nil {
-				return nil, err
-			}
+// overlayHasGoFile checks if the overlay contains a go file in given directory.
+func (c *LoadConfig) overlayHasGoFile(dir string) bool {
+	for fileName := range c.Overlay {
+		if !strings.HasSuffix(fileName, ".go") {
+			continue
+		}
+		if abs, err := c.absPath(fileName); err == nil && filepath.Dir(abs) == dir {
+			return true
 		}
-		pkgName, err := PackageNameOfDir(pkgPath)
+	}
+	return false
+}
+
+// absOverlay gets the overlay with the absolute file paths, as required by the packages loader.
+func (c *LoadConfig) absOverlay() (map[string][]byte, error) {
+	if len(c.Overlay) == 0 {
+		return nil, nil
+	}
+	result := make(map[string][]byte, len(c.Overlay))
+	for fileName, content := range c.Overlay {
+		abs, err := c.absPath(fileName)
 		if err != nil {
 			return nil, err
 		}
-		pkgNames = append(pkgNames, pkgName)
+		result[abs] = content
+	}
+	return result, nil
+}
+
+// absPath gets the absolute path resolved against the loader working directory.
+func (c *LoadConfig) absPath(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	if c.Dir == "" {
+		return filepath.Abs(path)
+	}
+	dir, err := filepath.Abs(c.Dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path), nil
+}
+
+// environ gets the environment of the loader.
+func (c *LoadConfig) environ() []string {
+	if c.Env == nil {
+		return os.Environ()
+	}
+	return append([]string(nil), c.Env...)
+}
+
+// getenv gets the value of the environment variable of the loader. The last value of the variable is used.
+func (c *LoadConfig) getenv(key string) string {
+	if c.Env == nil {
+		return os.Getenv(key)
+	}
+	for i := len(c.Env) - 1; i >= 0; i-- {
+		if v := strings.TrimPrefix(c.Env[i], key+"="); v != c.Env[i] {
+			return v
+		}
+	}
+	return ""
+}
+
+// load loads the packages with the config Driver or the packages.Load function. The packages config is run
+// within the loader working directory and environment.
+func (c *LoadConfig) load(pkgCfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+	if pkgCfg.Dir == "" {
+		pkgCfg.Dir = c.Dir
+	}
+	if pkgCfg.Env == nil && c.Env != nil {
+		pkgCfg.Env = c.environ()
 	}
-	pkgNames = append(pkgNames, cfg.PkgNames...)
-	return pkgNames, nil
+	if c.Driver != nil {
+		return c.Driver(pkgCfg, patterns...)
+	}
+	return packages.Load(pkgCfg, patterns...)
 }
 
 func (p *packageMap) resolveLoadedPackages(pkgNames []string) (result []string) {
@@ -157,24 +384,46 @@ func (p *packageMap) resolveLoadedPackages(pkgNames []string) (result []string)
 	return result
 }
 
-func (p *packageMap) parsePackages(cfg *LoadConfig, newPkgs ...*packages.Package) {
-	now := time.Now()
-
+func (p *packageMap) parsePackages(ctx context.Context, cfg *LoadConfig, newPkgs ...*packages.Package) error {
 	var pkgs []*packages.Package
 	for _, pkg := range newPkgs {
-		// Check if the package is not already scanned.
-		if _, ok := p.read(pkg.PkgPath); !ok {
+		if isTestMain(pkg) {
+			continue
+		}
+		// Check if the package is not already scanned. The scanned packages are parsed again only in order to merge
+		// the declarations from another build context.
+		if _, ok := p.read(pkg.PkgPath); !ok || p.merge {
 			pkgs = append(pkgs, pkg)
 		}
 	}
+	// The test variants of the packages contains also the test files declarations, thus these are preferred
+	// over the packages with the same path.
+	sort.SliceStable(pkgs, func(i, j int) bool { return isTestVariant(pkgs[i]) && !isTestVariant(pkgs[j]) })
 	if len(pkgs) == 0 {
-		return
+		return nil
 	}
 
-	initWg, finishGroup := &sync.WaitGroup{}, &sync.WaitGroup{}
+	for _, pkg := range pkgs {
+		p.setRequested(pkg.PkgPath)
+	}
+	excluded := func(path string) bool { return p.excludesDependency(cfg, path) }
 	packageMap := map[string]*importedPackage{}
 	for _, pkg := range pkgs {
-		getAllImports(pkg, packageMap)
+		if cfg.LazyDependencies {
+			// Only the requested packages are parsed, the dependencies are mapped lazily.
+			addImportedPackage(pkg, packageMap)
+			continue
+		}
+		getAllImports(pkg, packageMap, excluded)
+	}
+	if !p.merge {
+		// The dependencies that already exists in the map are not parsed again, so that the types of the packages
+		// in the map keeps referencing them.
+		for path := range packageMap {
+			if _, ok := p.read(path); ok {
+				delete(packageMap, path)
+			}
+		}
 	}
 	pkgList := make([]*importedPackage, len(packageMap))
 	var i int
@@ -183,12 +432,55 @@ func (p *packageMap) parsePackages(cfg *LoadConfig, newPkgs ...*packages.Package
 		i++
 	}
 	sort.Slice(pkgList, func(i, j int) bool { return pkgList[i].importNo < pkgList[j].importNo })
-	initWg.Add(len(packageMap))
-	finishGroup.Add(len(packageMap))
+
+	p.Lock()
+	existing := make(map[string]struct{}, len(p.pkgMap))
+	for path := range p.pkgMap {
+		existing[path] = struct{}{}
+	}
+	p.Unlock()
+
+	var cache *packageCache
+	if cfg.CacheDir != "" && !cfg.LazyDependencies && !p.merge {
+		var err error
+		if cache, err = newPackageCache(cfg, p.build, p); err != nil {
+			return err
+		}
+		var parsed []*importedPackage
+		elapsed := p.runPhase(cfg, PhaseCache, func() {
+			parsed = p.restoreCachedPackages(cache, pkgList)
+		})
+		p.stats.Cached += len(pkgList) - len(parsed)
+		cfg.log(LogInfo, "packages restored from the cache", "packages", len(pkgList)-len(parsed), "elapsed", elapsed)
+		pkgList = parsed
+	}
+	if p.parsed == nil {
+		p.parsed = map[string]struct{}{}
+	}
+	for path := range packageMap {
+		p.parsed[path] = struct{}{}
+	}
+
+	if cfg.LazyDependencies {
+		visited := map[string]struct{}{}
+		for path := range packageMap {
+			visited[path] = struct{}{}
+		}
+		for _, importedPkg := range pkgList {
+			p.registerLazyImports(cfg, importedPkg.pkgPkg.Fset, importedPkg.typesPkg, visited, excluded)
+		}
+	}
 
 	rootPkgs := map[*gotypes.Package]*rootPackage{}
-	for _, importedPkg := range pkgList {
+	var annotated []*rootPackage
+	if p.build != nil {
+		// The packages with the same files as the ones already merged are only annotated with the build context.
+		pkgList, annotated = p.splitMergedPackages(ctx, cfg, pkgList, rootPkgs)
+	}
+	rootList := make([]*rootPackage, len(pkgList))
+	for i, importedPkg := range pkgList {
 		rootPkg := &rootPackage{
+			ctx:             ctx,
 			rootPackages:    rootPkgs,
 			pkgPkg:          importedPkg.pkgPkg,
 			typesPkg:        importedPkg.typesPkg,
@@ -197,39 +489,145 @@ func (p *packageMap) parsePackages(cfg *LoadConfig, newPkgs ...*packages.Package
 			typesInProgress: map[string]types.Type{},
 			mappedAliases:   map[string]struct{}{},
 			namedAliases:    map[string]*gotypes.Named{},
+			merged:          map[string]struct{}{},
 		}
 		rootPkgs[importedPkg.typesPkg] = rootPkg
+		rootList[i] = rootPkg
+	}
+
+	// All the packages are scaffolded before their types are resolved, as the types might reference
+	// the types of other packages.
+	reported := p.errorCount()
+	pool := newWorkerPool(cfg.Concurrency)
+	elapsed := p.runPhase(cfg, PhaseParse, func() {
+		pool.run(rootList, (*rootPackage).initTypePkg)
+		pool.run(rootList, (*rootPackage).finishTypePkg)
+		pool.run(annotated, (*rootPackage).annotatePackage)
+	})
+	if pool.peak > p.stats.PeakWorkers {
+		p.stats.PeakWorkers = pool.peak
 	}
 
-	for _, rootPkg := range rootPkgs {
-		go rootPkg.parseTypePkg(initWg, finishGroup)
+	if err := ctx.Err(); err != nil {
+		p.removeNewPackages(existing)
+		return err
+	}
+	if !cfg.BestEffort && p.errorCount() > reported {
+		// The packages that failed to parse are not returned unless in the best effort mode.
+		p.removeNewPackages(existing)
+		return ErrPackageErrors
+	}
+	if cache != nil {
+		p.writeCachedPackages(cache, pkgList)
 	}
 
-	finishGroup.Wait()
+	cfg.log(LogInfo, "gentools packages parsed", "packages", len(pkgList), "elapsed", elapsed)
+	return nil
+}
 
-	if cfg.Verbose {
-		log.Printf("gentools packages parsed in %s\n", time.Since(now))
+// removeNewPackages removes the partially parsed packages, that didn't exist before the load.
+func (p *packageMap) removeNewPackages(existing map[string]struct{}) {
+	p.Lock()
+	defer p.Unlock()
+	for path := range p.pkgMap {
+		if _, ok := existing[path]; !ok {
+			delete(p.pkgMap, path)
+		}
 	}
 }
 
+// splitMergedPackages splits the packages into the ones to parse, and the ones with the files already parsed
+// under another build context. The latter are returned as the roots of the already parsed packages.
+func (p *packageMap) splitMergedPackages(ctx context.Context, cfg *LoadConfig, pkgList []*importedPackage, rootPkgs map[*gotypes.Package]*rootPackage) ([]*importedPackage, []*rootPackage) {
+	var (
+		parsed    []*importedPackage
+		annotated []*rootPackage
+	)
+	for _, importedPkg := range pkgList {
+		key := fileSetKey(importedPkg.pkgPkg)
+		if _, ok := p.fileSets[key]; ok && p.merge {
+			if refPkg, ok := p.read(importedPkg.typesPkg.Path()); ok {
+				annotated = append(annotated, &rootPackage{
+					ctx:             ctx,
+					rootPackages:    rootPkgs,
+					pkgPkg:          importedPkg.pkgPkg,
+					typesPkg:        importedPkg.typesPkg,
+					refPkg:          refPkg,
+					pkgMap:          p,
+					loadConfig:      cfg,
+					typesInProgress: map[string]types.Type{},
+					mappedAliases:   map[string]struct{}{},
+					namedAliases:    map[string]*gotypes.Named{},
+					merging:         true,
+					merged:          map[string]struct{}{},
+				})
+				continue
+			}
+		}
+		p.fileSets[key] = struct{}{}
+		parsed = append(parsed, importedPkg)
+	}
+	return parsed, annotated
+}
+
+// fileSetKey gets the key of the package path along with its sorted file names.
+func fileSetKey(pkg *packages.Package) string {
+	files := append([]string{pkg.PkgPath}, pkg.GoFiles...)
+	sort.Strings(files[1:])
+	return strings.Join(files, "\n")
+}
+
 type importedPackage struct {
 	pkgPkg   *packages.Package
 	typesPkg *gotypes.Package
 	importNo int
 }
 
-func getAllImports(pkg *packages.Package, imports map[string]*importedPackage) {
-	typesPkg := pkg.Types
-	imports[typesPkg.Path()] = &importedPackage{pkgPkg: pkg, typesPkg: typesPkg, importNo: len(typesPkg.Imports())}
+// getAllImports adds given package along with all its imports. The excluded packages and their imports
+// are not added, unless imported by another package.
+func getAllImports(pkg *packages.Package, imports map[string]*importedPackage, excluded func(path string) bool) {
+	if !addImportedPackage(pkg, imports) {
+		return
+	}
 	for path, sub := range pkg.Imports {
-		if _, ok := imports[path]; ok {
+		if _, ok := imports[path]; ok || excluded(path) {
+			continue
+		}
+		getAllImports(sub, imports, excluded)
+	}
+}
+
+// mergeImportPaths adds the import paths of given package to the sorted paths list.
+func mergeImportPaths(paths []string, pkg *gotypes.Package) []string {
+	for _, imp := range pkg.Imports() {
+		i := sort.SearchStrings(paths, imp.Path())
+		if i < len(paths) && paths[i] == imp.Path() {
 			continue
 		}
-		getAllImports(sub, imports)
+		paths = append(paths, "")
+		copy(paths[i+1:], paths[i:])
+		paths[i] = imp.Path()
+	}
+	return paths
+}
+
+// addImportedPackage adds given package to the imports. It returns false if the package could not be added.
+func addImportedPackage(pkg *packages.Package, imports map[string]*importedPackage) bool {
+	typesPkg := pkg.Types
+	if typesPkg == nil {
+		// The package that failed to load doesn't have its types defined.
+		return false
+	}
+	if _, ok := imports[typesPkg.Path()]; ok {
+		// The package with the same path (i.e. its test variant) is already imported.
+		return false
 	}
+	imports[typesPkg.Path()] = &importedPackage{pkgPkg: pkg, typesPkg: typesPkg, importNo: len(typesPkg.Imports())}
+	return true
 }
 
 type rootPackage struct {
+	ctx             context.Context
 	pkgPkg          *packages.Package
 	typesPkg        *gotypes.Package
 	refPkg          *types.Package
@@ -239,33 +637,113 @@ type rootPackage struct {
 	namedAliases    map[string]*gotypes.Named
 	loadConfig      *LoadConfig
 	declNames       []string
+	declNodes       map[token.Pos]ast.Node
 	typesInProgress map[string]types.Type
+	// merging states if the package declarations are merged into the package parsed under another build context.
+	merging bool
+	// merged are the names of the declarations already parsed under another build context.
+	merged map[string]struct{}
+	// constraints are the build constraint expressions of the package files, by the file names.
+	constraints map[string]string
+	// fileNodes are the declaration nodes by their offsets, of the files parsed on demand by the file names.
+	fileNodes map[string]map[int]ast.Node
+	// failed states if the package initialization failed unexpectedly.
+	failed bool
+	// elapsed is the duration of parsing the package.
+	elapsed time.Duration
+	// lazy states if the root maps the types of the lazy package. Its types are set in the package once finished.
+	lazy bool
+	// unpublished are the names of the lazy package types in progress, that are not set in the package yet.
+	unpublished []string
+	// instances are the generic type instances in progress, that are not set in the package map yet.
+	instances map[*gotypes.TypeName][]namedInstance
+	// instanceDepth is the number of the nested generic type instances in progress.
+	instanceDepth int
 }
 
 func (r *rootPackage) setTypeInProgress(name string, tp types.Type) {
 	r.typesInProgress[name] = tp
+	if r.lazy {
+		r.unpublished = append(r.unpublished, name)
+		return
+	}
 	r.refPkg.SetNamedType(name, tp)
 }
 
-func (r *rootPackage) parseTypePkg(initWg, fg *sync.WaitGroup) {
-	p := r.pkgMap.newPackage(r.typesPkg.Path(), r.typesPkg.Name())
+// initTypePkg creates the package and scaffolds its objects.
+func (r *rootPackage) initTypePkg() {
+	r.loadConfig.progress(Progress{Kind: PackageStarted, Phase: PhaseParse, PkgPath: r.typesPkg.Path()})
+	now := time.Now()
+	defer func() {
+		r.elapsed += time.Since(now)
+		// The unexpected failure of a single package should not stop the whole load.
+		if rec := recover(); rec != nil {
+			r.errorf("", token.NoPos, "parsing package failed: %v", rec)
+			r.failed = true
+			r.loadConfig.progress(Progress{Kind: PackageFinished, Phase: PhaseParse, PkgPath: r.typesPkg.Path(), Elapsed: r.elapsed})
+		}
+	}()
+	var p *types.Package
+	if r.pkgMap.merge {
+		p, r.merging = r.pkgMap.read(r.typesPkg.Path())
+	}
+	if !r.merging {
+		p = r.pkgMap.newPackage(r.typesPkg.Path(), r.typesPkg.Name())
+		p.ForTest = testedPackage(r.pkgPkg)
+		p.Module, p.Dir = packageModule(r.pkgPkg), packageDir(r.pkgPkg)
+	}
+	p.Imports = mergeImportPaths(p.Imports, r.typesPkg)
 
 	r.refPkg = p
-	r.scaffoldPackageObjects()
-	initWg.Done()
-	initWg.Wait()
+	if r.ctx.Err() == nil {
+		r.scaffoldPackageObjects()
+	}
+}
 
+// finishTypePkg resolves the scaffolded types, and parses the package declarations. It is expected to be called
+// after all the loaded packages are initialized.
+func (r *rootPackage) finishTypePkg() {
+	if r.failed {
+		return
+	}
+	now := time.Now()
+	defer func() {
+		r.elapsed += time.Since(now)
+		if rec := recover(); rec != nil {
+			r.errorf("", token.NoPos, "parsing package failed: %v", rec)
+		}
+		r.loadConfig.progress(Progress{Kind: PackageFinished, Phase: PhaseParse, PkgPath: r.typesPkg.Path(), Elapsed: r.elapsed})
+	}()
+
+	// The cancellation is checked between each parsing stage, and by the stages for each parsed object.
+	if r.ctx.Err() != nil {
+		return
+	}
+	p := r.refPkg
 	s := r.typesPkg.Scope()
 	r.resolveInProgressTypes(s, p)
+	if r.ctx.Err() != nil {
+		return
+	}
 	r.resolveIdentAliases()
+	if r.ctx.Err() != nil {
+		return
+	}
 	r.defineDeclarations(s, p)
-	if r.loadConfig.WithComments {
+	if r.pkgMap.build != nil {
+		r.annotateBuildContext(s, *r.pkgMap.build)
+	}
+	if p.ForTest != "" && !r.merging {
+		r.parseExamples(p)
+	}
+	if r.loadConfig.parsesComments() && r.ctx.Err() == nil {
+		r.parseFiles(p)
 		r.parseComments(p)
 	}
-	fg.Done()
 }
 
 func (r *rootPackage) resolveIdentAliases() {
+	retries := map[*ast.TypeSpec]int{}
 	for _, file := range r.pkgPkg.Syntax {
 		for _, decl := range file.Decls {
 			dt, ok := decl.(*ast.GenDecl)
@@ -274,6 +752,9 @@ func (r *rootPackage) resolveIdentAliases() {
 			}
 			specs := dt.Specs
 			for i := 0; i < len(specs); i++ {
+				if r.ctx.Err() != nil {
+					return
+				}
 				spec := specs[i]
 				ts, ok := spec.(*ast.TypeSpec)
 				if !ok {
@@ -283,18 +764,18 @@ func (r *rootPackage) resolveIdentAliases() {
 				if _, isAlias := r.mappedAliases[ts.Name.Name]; !isAlias {
 					continue
 				}
-				if ts.Name.Name == "PlaceholderEnum" {
-					func() {}()
-				}
 
 				at, err := r.extractAliasExpr(file, ts.Type)
 				if err != nil {
-					if errors.Is(err, errIdentNotFound) {
+					if errors.Is(err, errIdentNotFound) && retries[ts] < len(dt.Specs) {
 						// Add the specification on the back of the queue.
+						retries[ts]++
 						specs = append(specs, spec)
 						continue
 					}
-					panic(err)
+					// The type wrapper is still usable, only its underlying type is unresolved.
+					r.warnf(ts.Name.Name, ts.Name.Pos(), "resolving type wrapper failed: %v", err)
+					at = r.refPkg.NewUnresolved(gotypes.ExprString(ts.Type), err.Error(), ts.Name.Name, r.tokenPosition(ts.Type.Pos()))
 				}
 
 				named, ok := r.namedAliases[ts.Name.Name]
@@ -308,7 +789,8 @@ func (r *rootPackage) resolveIdentAliases() {
 				}
 				alias, ok := ip.(*types.Alias)
 				if !ok {
-					panic("expected to be an alias")
+					r.errorf(ts.Name.Name, ts.Name.Pos(), "type wrapper expected to be an alias but is: %T", ip)
+					continue
 				}
 
 				r.finishNamedAliasType(named, alias, at)
@@ -332,10 +814,17 @@ func (r *rootPackage) resolveInProgressTypes(s *gotypes.Scope, p *types.Package)
 	}
 
 	for _, tpl := range inProgress {
+		if r.ctx.Err() != nil {
+			return
+		}
 		name, tt := tpl.name, tpl.tp
 
 		tp := typesScope.Lookup(name)
-		switch t := tp.Type().(type) {
+		if alias, ok := tt.(*types.Alias); ok && alias.TypeAlias {
+			r.finishTypeAlias(tp, alias)
+			continue
+		}
+		switch t := unalias(tp.Type()).(type) {
 		case *gotypes.Named:
 			if _, ok := r.mappedAliases[name]; ok {
 				r.namedAliases[name] = t
@@ -343,13 +832,12 @@ func (r *rootPackage) resolveInProgressTypes(s *gotypes.Scope, p *types.Package)
 			}
 
 			if ok := r.finishNamedType(t, tt); !ok {
-				if r.loadConfig.Verbose {
-					log.Printf("package: %s, type: %s not mapped\n", p.Path, t.Obj().Name())
-				}
+				r.warnf(name, tp.Pos(), "type not mapped")
 				continue
 			}
 		case *gotypes.Signature:
 			if !r.finishNamedFunc(t, tt) {
+				r.warnf(name, tp.Pos(), "function not mapped")
 				continue
 			}
 		}
@@ -358,6 +846,9 @@ func (r *rootPackage) resolveInProgressTypes(s *gotypes.Scope, p *types.Package)
 
 func (r *rootPackage) defineDeclarations(s *gotypes.Scope, p *types.Package) {
 	for _, name := range r.declNames {
+		if r.ctx.Err() != nil {
+			return
+		}
 		obj := s.Lookup(name)
 		switch ot := obj.(type) {
 		case *gotypes.Const:
@@ -366,26 +857,32 @@ func (r *rootPackage) defineDeclarations(s *gotypes.Scope, p *types.Package) {
 				continue
 			}
 			if err := p.NewConstant(ot.Name(), declType, ot.Val()); err != nil {
-				if r.loadConfig.Verbose {
-					fmt.Println(err)
-				}
+				r.warnf(name, ot.Pos(), "%v", err)
+				continue
 			}
+			r.setDeclarationPosition(p, ot)
 		case *gotypes.Var:
 			declType, ok := r.dereferenceType(p, ot.Type())
 			if !ok {
 				continue
 			}
 			if err := p.NewVariable(ot.Name(), declType); err != nil {
-				if r.loadConfig.Verbose {
-					fmt.Println(err)
-				}
+				r.warnf(name, ot.Pos(), "%v", err)
+				continue
 			}
+			r.setDeclarationPosition(p, ot)
 		default:
 			continue
 		}
 	}
 }
 
+func (r *rootPackage) setDeclarationPosition(p *types.Package, obj gotypes.Object) {
+	decl := p.Declarations[obj.Name()]
+	decl.Pos = r.objectPosition(obj)
+	p.Declarations[obj.Name()] = decl
+}
+
 func (r *rootPackage) parseComments(p *types.Package) {
 	for _, file := range r.pkgPkg.Syntax {
 	declLoop:
@@ -396,11 +893,13 @@ func (r *rootPackage) parseComments(p *types.Package) {
 				for _, spec := range dt.Specs {
 					switch st := spec.(type) {
 					case *ast.TypeSpec:
+						if _, ok := r.merged[st.Name.Name]; ok {
+							// The comments were already parsed under another build context.
+							continue
+						}
 						tp, ok := p.Types[st.Name.Name]
 						if !ok {
-							if r.loadConfig.Verbose {
-								log.Printf("type: '%s' not found in the pkg declaration", st.Name.Name)
-							}
+							r.loadConfig.log(LogDebug, "type not found in the package declaration", "package", p.Path, "type", st.Name.Name)
 							continue
 						}
 						var comment string
@@ -410,6 +909,13 @@ func (r *rootPackage) parseComments(p *types.Package) {
 						if comment == "" && dt.Doc != nil {
 							comment = dt.Doc.Text()
 						}
+						doc := st.Doc
+						if doc == nil && !dt.Lparen.IsValid() {
+							doc = dt.Doc
+						}
+						markers := r.parseMarkers(st.Name.Name, doc, st.Comment)
+						// The markers of the type are not propagated to the aliased named type.
+						var aliased bool
 
 					ptrLoop:
 						for {
@@ -420,62 +926,116 @@ func (r *rootPackage) parseComments(p *types.Package) {
 								continue ptrLoop
 							case *types.Struct:
 								tt.Comment = comment
+								if !aliased {
+									tt.Markers = markers
+								}
 								structType, ok := r.extractStructExpr(file, st.Type)
 								if !ok {
-									if r.loadConfig.Verbose {
-										log.Printf("getting ast struct type failed. package: '%s', name: '%s'\n", r.refPkg.Path, st.Name.Name)
-									}
+									r.loadConfig.log(LogDebug, "getting ast struct type failed", "package", p.Path, "type", st.Name.Name)
 									continue specLoop
 								}
 
-								for j, field := range structType.Fields.List {
-									var fc string
-									if field.Doc != nil {
-										fc = field.Doc.Text()
+								// The field with multiple names i.e.: 'A, B int' defines a struct field for each name.
+								// The fields are matched by their indexes, as the unexported ones might not be mapped.
+								fields := make(map[int]*types.StructField, len(tt.Fields))
+								for k := range tt.Fields {
+									if len(tt.Fields[k].Index) != 0 {
+										fields[tt.Fields[k].Index[0]] = &tt.Fields[k]
+									}
+								}
+								var j int
+								for _, field := range structType.Fields.List {
+									n := len(field.Names)
+									if n == 0 {
+										// The embedded field.
+										n = 1
 									}
-									if len(tt.Fields) != structType.Fields.NumFields() {
-										fmt.Println("Different!")
+									// The markers are parsed once per field, so that the invalid ones are reported once.
+									var (
+										fieldMarkers types.Markers
+										parsed       bool
+									)
+									for ; n > 0; n, j = n-1, j+1 {
+										sf, ok := fields[j]
+										if !ok {
+											continue
+										}
+										if !parsed {
+											fieldMarkers, parsed = r.parseMarkers(st.Name.Name+"."+sf.Name, field.Doc, field.Comment), true
+										}
+										sf.Comment = commentText(field.Doc)
+										sf.LineComment = commentText(field.Comment)
+										sf.Markers = fieldMarkers
 									}
-									tt.Fields[j].Comment = fc
 								}
 							case *types.Interface:
 								tt.Comment = comment
+								if !aliased {
+									tt.Markers = markers
+								}
 
 								interfaceType, ok := r.extractInterfaceExpr(file, st.Type)
 								if !ok {
-									if r.loadConfig.Verbose {
-										log.Printf("Getting ast interface type failed.  Package: '%s', name: '%s'", r.pkgPkg.Name, st.Name.Name)
-									}
+									r.loadConfig.log(LogDebug, "getting ast interface type failed", "package", p.Path, "type", st.Name.Name)
 									continue specLoop
 								}
 
-								for j, method := range interfaceType.Methods.List {
-									var fc string
-									if method.Doc != nil {
-										fc = method.Doc.Text()
+								for _, method := range interfaceType.Methods.List {
+									// The embedded interfaces and type terms doesn't have names.
+									for _, name := range method.Names {
+										if method.Doc != nil {
+											tt.SetMethodComment(name.Name, method.Doc.Text())
+										}
+										if method.Comment != nil {
+											tt.SetMethodLineComment(name.Name, method.Comment.Text())
+										}
+										if methodMarkers := r.parseMarkers(st.Name.Name+"."+name.Name, method.Doc, method.Comment); len(methodMarkers) != 0 {
+											tt.SetMethodMarkers(name.Name, methodMarkers)
+										}
 									}
-									tt.Methods[j].Comment = fc
 								}
 							case *types.Alias:
 								tt.Comment = comment
+								if !aliased {
+									tt.Markers = markers
+								}
+								if tt.TypeAlias {
+									// The aliased type is declared elsewhere and keeps its own comments.
+									continue specLoop
+								}
+								aliased = true
 								tp = tt.Type
 								continue ptrLoop
 							case *types.Function:
 								tt.Comment = comment
+								if !aliased {
+									tt.Markers = markers
+								}
 							}
 							continue specLoop
 						}
 					case *ast.ValueSpec:
-						if st.Doc == nil {
+						doc := st.Doc
+						if doc == nil && !dt.Lparen.IsValid() {
+							// The doc comment of the single specification declaration i.e.: 'const A = 1'.
+							doc = dt.Doc
+						}
+						comment, lineComment := commentText(doc), commentText(st.Comment)
+						if doc == nil && st.Comment == nil {
 							continue
 						}
 
+						// Each name of the specification i.e.: 'A, B = 1, 2' shares its comments.
 						for _, name := range st.Names {
+							if _, ok := r.merged[name.Name]; ok {
+								continue
+							}
 							decl, ok := p.Declarations[name.Name]
 							if !ok {
 								continue
 							}
-							decl.Comment = st.Doc.Text()
+							decl.Comment, decl.LineComment = comment, lineComment
+							decl.Markers = r.parseMarkers(name.Name, doc, st.Comment)
 							p.Declarations[name.Name] = decl
 						}
 					}
@@ -527,29 +1087,38 @@ func (r *rootPackage) parseComments(p *types.Package) {
 						break
 					}
 					if funType == nil {
-						if r.loadConfig.Verbose {
-							log.Printf("method: '%s' not found in the pkg: %s declaration\n", dt.Name.Name, p.Path)
-						}
+						r.loadConfig.log(LogDebug, "method not found in the package declaration", "package", p.Path, "method", dt.Name.Name)
 						continue
 					}
 				} else {
+					if _, ok := r.merged[dt.Name.Name]; ok {
+						continue
+					}
 					var ok bool
 					funType, ok = p.GetFunction(dt.Name.Name)
 					if !ok {
-						if r.loadConfig.Verbose {
-							log.Printf("function: '%s' not found in the pkg: %s declaration\n", dt.Name.Name, p.Path)
-						}
+						r.loadConfig.log(LogDebug, "function not found in the package declaration", "package", p.Path, "function", dt.Name.Name)
 						continue
 					}
 				}
 				funType.Comment = dt.Doc.Text()
+				funType.Markers = r.parseMarkers(dt.Name.Name, dt.Doc)
 			}
 		}
 	}
 }
 
+// commentText gets the text of given comment group, or an empty string if it is not defined.
+func commentText(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	return cg.Text()
+}
+
 func (r *rootPackage) scaffoldPackageObjects() {
 	s := r.typesPkg.Scope()
+	r.indexDeclarationNodes()
 
 	for _, file := range r.pkgPkg.Syntax {
 		for _, decl := range file.Decls {
@@ -562,6 +1131,10 @@ func (r *rootPackage) scaffoldPackageObjects() {
 				if !ok {
 					continue
 				}
+				if ts.Assign.IsValid() {
+					// The type aliases i.e.: 'type Reader = io.Reader' are resolved from the aliased type.
+					continue
+				}
 				tsType := ts.Type
 
 				// This is a workaround only for the invalid type wrapper.
@@ -582,48 +1155,69 @@ func (r *rootPackage) scaffoldPackageObjects() {
 	}
 
 	for _, name := range s.Names() {
-		obj := s.Lookup(name)
-		switch ot := obj.(type) {
-		case *gotypes.TypeName:
-			if _, isAlias := r.mappedAliases[name]; isAlias {
-				wt := &types.Alias{
-					Pkg:       r.refPkg,
-					AliasName: name,
-				}
-				r.setTypeInProgress(name, wt)
-				continue
-			}
+		if !r.pkgMap.mapsName(r.loadConfig, r.typesPkg.Path(), name) {
+			continue
+		}
+		if r.merging && r.isDeclared(name) {
+			r.merged[name] = struct{}{}
+			delete(r.mappedAliases, name)
+			continue
+		}
+		r.scaffoldObject(name, s.Lookup(name))
+	}
+}
 
-			switch tp := ot.Type().(type) {
-			case *gotypes.Named:
-				r.scaffoldNamedObject(tp, name)
-			default:
-				wt := &types.Alias{
-					Pkg:       r.refPkg,
-					AliasName: name,
-				}
-				r.setTypeInProgress(name, wt)
-			}
-		case *gotypes.Func:
-			sig, ok := ot.Type().(*gotypes.Signature)
-			if !ok {
-				continue
-			}
-			if sig.Recv() != nil {
-				// This is a method which should not be extracted as function.
-				continue
+func (r *rootPackage) scaffoldObject(name string, obj gotypes.Object) {
+	switch ot := obj.(type) {
+	case *gotypes.TypeName:
+		if ot.IsAlias() {
+			wt := &types.Alias{
+				Pkg:       r.refPkg,
+				AliasName: name,
+				Pos:       r.objectPosition(ot),
+				TypeAlias: true,
 			}
-			fT := &types.Function{
-				Pkg:      r.refPkg,
-				FuncName: name,
+			r.setTypeInProgress(name, wt)
+			return
+		}
+		if _, isAlias := r.mappedAliases[name]; isAlias {
+			wt := &types.Alias{
+				Pkg:       r.refPkg,
+				AliasName: name,
+				Pos:       r.objectPosition(ot),
 			}
-			r.setTypeInProgress(name, fT)
-		case *gotypes.Const, *gotypes.Var:
-			r.declNames = append(r.declNames, name)
-			continue
+			r.setTypeInProgress(name, wt)
+			return
+		}
+
+		switch tp := unalias(ot.Type()).(type) {
+		case *gotypes.Named:
+			r.scaffoldNamedObject(tp, name)
 		default:
-			continue
+			wt := &types.Alias{
+				Pkg:       r.refPkg,
+				AliasName: name,
+			}
+			r.setTypeInProgress(name, wt)
+		}
+		setTypePosition(r.typesInProgress[name], r.objectPosition(ot))
+	case *gotypes.Func:
+		sig, ok := ot.Type().(*gotypes.Signature)
+		if !ok {
+			return
+		}
+		if sig.Recv() != nil {
+			// This is a method which should not be extracted as function.
+			return
 		}
+		fT := &types.Function{
+			Pkg:      r.refPkg,
+			FuncName: name,
+			Pos:      r.objectPosition(ot),
+		}
+		r.setTypeInProgress(name, fT)
+	case *gotypes.Const, *gotypes.Var:
+		r.declNames = append(r.declNames, name)
 	}
 }
 
@@ -659,16 +1253,23 @@ func (r *rootPackage) finishNamedType(named *gotypes.Named, t types.Type) bool {
 	case *types.Interface:
 		return r.finishNamedInterfaceType(named, ot)
 	case *types.Alias:
-		underlying, ok := r.dereferenceType(r.refPkg, named.Underlying())
-		if !ok {
-			panic(fmt.Sprintf("no underlying alias type found: %v", named.Underlying()))
+		var ok bool
+		if ot.TypeParams, ok = r.parseTypeParams(r.refPkg, named.TypeParams()); !ok {
+			return false
 		}
+		underlying := r.resolveType(r.refPkg, named.Underlying(), r.typeExpr(named.Obj()), named.Obj().Name(), r.objectPosition(named.Obj()))
 		return r.finishNamedAliasType(named, ot, underlying)
 	default:
-		panic(fmt.Sprintf("invalid type %T", t))
+		r.errorf(named.Obj().Name(), named.Obj().Pos(), "invalid named type: %T", t)
+		return false
 	}
 }
 
+// finishTypeAlias resolves the type referred by the type alias i.e.: 'io.Reader' for the 'type Reader = io.Reader'.
+func (r *rootPackage) finishTypeAlias(obj gotypes.Object, alias *types.Alias) {
+	alias.Type = r.resolveType(r.refPkg, unalias(obj.Type()), r.typeExpr(obj), obj.Name(), r.objectPosition(obj))
+}
+
 func (r *rootPackage) finishNamedAliasType(named *gotypes.Named, alias *types.Alias, underlying types.Type) bool {
 	p := r.refPkg
 	alias.Type = underlying
@@ -691,17 +1292,276 @@ func (r *rootPackage) parseNamedType(et *gotypes.Named) (types.Type, bool) {
 		t, ok := types.GetBuiltInType(et.Obj().Name())
 		return t, ok
 	}
-	p, ok := r.pkgMap.read(et.Obj().Pkg().Path())
+	path, name := et.Obj().Pkg().Path(), et.Obj().Name()
+	if _, ok := r.pkgMap.read(path); !ok && r.pkgMap.excludesDependency(r.loadConfig, path) {
+		return r.opaqueType(et)
+	}
+	if et.TypeArgs().Len() != 0 {
+		return r.parseNamedInstance(et)
+	}
+	p, ok := r.pkgMap.read(path)
+	if !ok {
+		if !r.loadConfig.LazyDependencies {
+			return nil, ok
+		}
+		// The package might not be listed in the imports of the export data.
+		p = r.pkgMap.lazyPackage(r.loadConfig, r.pkgPkg.Fset, et.Obj().Pkg())
+	}
+	tp, ok := r.packageType(p, name)
+	if !ok && !r.pkgMap.mapsName(r.loadConfig, path, name) {
+		// The unexported type of the dependency is not mapped in the ExportedOnly mode.
+		return r.opaqueType(et)
+	}
+	return tp, ok
+}
+
+// parseNamedInstance parses the instance of the generic named type. The instances in progress are kept
+// by the root, and set in the package map only once all the nested instances are finished, so that the concurrent
+// parsers never get the instance that is not fully parsed.
+func (r *rootPackage) parseNamedInstance(et *gotypes.Named) (types.Type, bool) {
+	if t, ok := r.readInstance(et); ok {
+		return t, true
+	}
+	if t, ok := r.pkgMap.readInstance(et); ok {
+		return t, true
+	}
+	if !r.pkgMap.mapsName(r.loadConfig, et.Obj().Pkg().Path(), et.Obj().Name()) {
+		return r.opaqueType(et)
+	}
+	origin, ok := r.parseNamedType(et.Origin())
 	if !ok {
-		return nil, ok
+		return nil, false
+	}
+	pkgr, ok := origin.(types.Packager)
+	if !ok {
+		return nil, false
+	}
+	p := pkgr.Package()
+
+	typeArgs := make([]types.Type, et.TypeArgs().Len())
+	for i := 0; i < et.TypeArgs().Len(); i++ {
+		at, ok := r.dereferenceType(p, et.TypeArgs().At(i))
+		if !ok {
+			return nil, false
+		}
+		typeArgs[i] = at
+	}
+
+	r.instanceDepth++
+	tp, ok := r.parseInstanceOf(p, et, origin, typeArgs)
+	r.instanceDepth--
+	if !ok {
+		// The instance that failed to parse is not published.
+		r.dropInstance(et)
+	}
+	if r.instanceDepth == 0 {
+		r.publishInstances()
+	}
+	return tp, ok
+}
+
+func (r *rootPackage) parseInstanceOf(p *types.Package, et *gotypes.Named, origin types.Type, typeArgs []types.Type) (types.Type, bool) {
+	var ok bool
+	switch ot := origin.(type) {
+	case *types.Struct:
+		if isOwnTypeParams(ot.TypeParams, typeArgs) {
+			return ot, true
+		}
+		underlying, ok := et.Underlying().(*gotypes.Struct)
+		if !ok {
+			return nil, false
+		}
+		st := &types.Struct{Pkg: p, Comment: ot.Comment, TypeName: ot.TypeName, TypeArgs: typeArgs, Origin: ot, Pos: ot.Pos}
+		r.stageInstance(et, st)
+		st.Fields = make([]types.StructField, underlying.NumFields())
+		if !r.parseStructFields(p, underlying, st) {
+			return nil, false
+		}
+		if st.Methods, ok = r.parseNamedMethods(p, et); !ok {
+			return nil, false
+		}
+		return st, true
+	case *types.Interface:
+		if isOwnTypeParams(ot.TypeParams, typeArgs) {
+			return ot, true
+		}
+		underlying, ok := et.Underlying().(*gotypes.Interface)
+		if !ok {
+			return nil, false
+		}
+		it := &types.Interface{
+			Pkg:           p,
+			Comment:       ot.Comment,
+			InterfaceName: ot.InterfaceName,
+			TypeArgs:      typeArgs,
+			Origin:        ot,
+			Pos:           ot.Pos,
+		}
+		r.stageInstance(et, it)
+		it.Methods = make([]types.Function, underlying.NumMethods())
+		if !r.parseInterfaceMethods(p, underlying, it) {
+			return nil, false
+		}
+		if !r.parseInterfaceEmbeddeds(p, underlying, it) {
+			return nil, false
+		}
+		return it, true
+	case *types.Alias:
+		if isOwnTypeParams(ot.TypeParams, typeArgs) {
+			return ot, true
+		}
+		at := &types.Alias{Pkg: p, Comment: ot.Comment, AliasName: ot.AliasName, TypeArgs: typeArgs, Origin: ot, Pos: ot.Pos}
+		r.stageInstance(et, at)
+		if at.Type, ok = r.dereferenceType(p, et.Underlying()); !ok {
+			return nil, false
+		}
+		if at.Methods, ok = r.parseNamedMethods(p, et); !ok {
+			return nil, false
+		}
+		return at, true
+	default:
+		return nil, false
+	}
+}
+
+// readInstance gets the instance of the generic named type in progress.
+func (r *rootPackage) readInstance(named *gotypes.Named) (types.Type, bool) {
+	for _, in := range r.instances[named.Obj()] {
+		if gotypes.Identical(in.named, named) {
+			return in.tp, true
+		}
+	}
+	return nil, false
+}
+
+// stageInstance stores the instance in progress, so that the recursive references could resolve it.
+func (r *rootPackage) stageInstance(named *gotypes.Named, tp types.Type) {
+	if r.instances == nil {
+		r.instances = map[*gotypes.TypeName][]namedInstance{}
+	}
+	r.instances[named.Obj()] = append(r.instances[named.Obj()], namedInstance{named: named, tp: tp})
+}
+
+// dropInstance removes the instance in progress.
+func (r *rootPackage) dropInstance(named *gotypes.Named) {
+	list := r.instances[named.Obj()]
+	for i, in := range list {
+		if gotypes.Identical(in.named, named) {
+			r.instances[named.Obj()] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// publishInstances sets the finished instances in the package map.
+func (r *rootPackage) publishInstances() {
+	for _, list := range r.instances {
+		for _, in := range list {
+			r.pkgMap.writeInstance(in.named, in.tp)
+		}
+	}
+	r.instances = nil
+}
+
+// isOwnTypeParams checks if the type arguments are the type parameters of the generic type.
+// This is the case of the generic type referenced within its own declaration i.e.: 'next *List[T]'.
+func isOwnTypeParams(params types.TypeParams, typeArgs []types.Type) bool {
+	if len(params) != len(typeArgs) {
+		return false
+	}
+	for i, param := range params {
+		if tp, ok := typeArgs[i].(*types.TypeParam); !ok || tp != param {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *rootPackage) parseNamedMethods(p *types.Package, named *gotypes.Named) ([]types.Function, bool) {
+	var methods []types.Function
+	for i := 0; i < named.NumMethods(); i++ {
+		xm, ok := r.parseMethod(p, named, i, true)
+		if !ok {
+			return nil, false
+		}
+		methods = append(methods, xm)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].FuncName < methods[j].FuncName })
+	return methods, true
+}
+
+func (r *rootPackage) parseTypeParams(p *types.Package, list *gotypes.TypeParamList) (types.TypeParams, bool) {
+	if list.Len() == 0 {
+		return nil, true
+	}
+	params := make(types.TypeParams, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		tp, ok := r.parseTypeParam(p, list.At(i))
+		if !ok {
+			return nil, false
+		}
+		params[i] = tp
+	}
+	return params, true
+}
+
+func (r *rootPackage) parseTypeParam(p *types.Package, tp *gotypes.TypeParam) (*types.TypeParam, bool) {
+	t, created := r.pkgMap.typeParam(tp)
+	if !created {
+		return t, true
+	}
+	constraint, ok := r.dereferenceType(p, tp.Constraint())
+	if !ok {
+		return nil, false
+	}
+	if it, ok := constraint.(*types.Interface); ok && it.InterfaceName == "" && it.IsEmpty() {
+		// An empty constraint interface is the 'any' constraint.
+		constraint = types.Any
+	}
+	t.Constraint = constraint
+	return t, true
+}
+
+// mapReceiverTypeParams maps the receiver type parameters of the generic type method into the type parameters
+// of the generic type. This way the receiver i.e.: 'func (l *List[T]) Push(v T)' refers to the generic type itself.
+func (r *rootPackage) mapReceiverTypeParams(s *gotypes.Signature) {
+	rtp := s.RecvTypeParams()
+	if rtp.Len() == 0 {
+		return
+	}
+	recv := s.Recv().Type()
+	if ptr, ok := recv.(*gotypes.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*gotypes.Named)
+	if !ok {
+		return
+	}
+	origin, ok := r.parseNamedType(named.Origin())
+	if !ok {
+		return
+	}
+	var params types.TypeParams
+	switch ot := origin.(type) {
+	case *types.Struct:
+		params = ot.TypeParams
+	case *types.Alias:
+		params = ot.TypeParams
+	}
+	if len(params) != rtp.Len() {
+		return
+	}
+	for i := 0; i < rtp.Len(); i++ {
+		r.pkgMap.setTypeParam(rtp.At(i), params[i])
 	}
-	return p.GetType(et.Obj().Name())
 }
 
 func (r *rootPackage) dereferenceType(p *types.Package, tp gotypes.Type) (types.Type, bool) {
-	switch et := tp.(type) {
+	switch et := unalias(tp).(type) {
 	case *gotypes.Named:
 		return r.parseNamedType(et)
+	case *gotypes.TypeParam:
+		return r.parseTypeParam(p, et)
 	case *gotypes.Struct:
 		return r.parseStructType(p, et)
 	case *gotypes.Interface:
@@ -762,7 +1622,7 @@ func (r *rootPackage) dereferenceType(p *types.Package, tp gotypes.Type) (types.
 		}
 		return ft, true
 	default:
-		log.Printf("type not found for dereferencing: %s, %T\n", et.String(), et)
+		r.loadConfig.log(LogWarning, "type not found for dereferencing", "package", r.typesPkg.Path(), "type", fmt.Sprintf("%s (%T)", et, et))
 		return nil, false
 	}
 }
@@ -773,8 +1633,11 @@ func (r *rootPackage) finishNamedInterfaceType(named *gotypes.Named, intf *types
 	if !ok {
 		return false
 	}
+	if intf.TypeParams, ok = r.parseTypeParams(p, named.TypeParams()); !ok {
+		return false
+	}
 	r.parseInterfaceMethods(p, it, intf)
-	return true
+	return r.parseInterfaceEmbeddeds(p, it, intf)
 }
 
 func (r *rootPackage) parseInterfaceType(p *types.Package, it *gotypes.Interface) (*types.Interface, bool) {
@@ -785,9 +1648,51 @@ func (r *rootPackage) parseInterfaceType(p *types.Package, it *gotypes.Interface
 			return nil, false
 		}
 	}
+	if ok := r.parseInterfaceEmbeddeds(p, it, intf); !ok {
+		return nil, false
+	}
 	return intf, true
 }
 
+// parseInterfaceEmbeddeds parses the interfaces and the type term unions explicitly embedded in the interface.
+func (r *rootPackage) parseInterfaceEmbeddeds(p *types.Package, it *gotypes.Interface, intf *types.Interface) bool {
+	intf.Implicit = it.IsImplicit()
+	for i := 0; i < it.NumEmbeddeds(); i++ {
+		if _, isInterface := it.EmbeddedType(i).Underlying().(*gotypes.Interface); isInterface {
+			et, ok := r.dereferenceType(p, it.EmbeddedType(i))
+			if !ok {
+				return false
+			}
+			intf.Embedded = append(intf.Embedded, et)
+		}
+		switch et := unalias(it.EmbeddedType(i)).(type) {
+		case *gotypes.Union:
+			u := types.Union{Terms: make([]types.Term, et.Len())}
+			for j := 0; j < et.Len(); j++ {
+				term := et.Term(j)
+				tt, ok := r.dereferenceType(p, term.Type())
+				if !ok {
+					return false
+				}
+				u.Terms[j] = types.Term{Tilde: term.Tilde(), Type: tt}
+			}
+			intf.Unions = append(intf.Unions, u)
+		default:
+			if ei, isInterface := et.Underlying().(*gotypes.Interface); isInterface && ei.IsMethodSet() {
+				// The methods of embedded interface are already in the interface method set.
+				continue
+			}
+			// A single type or a constraint interface embedded in the interface is a union with one term.
+			tt, ok := r.dereferenceType(p, et)
+			if !ok {
+				return false
+			}
+			intf.Unions = append(intf.Unions, types.Union{Terms: []types.Term{{Type: tt}}})
+		}
+	}
+	return true
+}
+
 func (r *rootPackage) parseInterfaceMethods(p *types.Package, it *gotypes.Interface, intf *types.Interface) bool {
 	for i := 0; i < it.NumMethods(); i++ {
 		xm, ok := r.parseMethod(p, it, i, false)
@@ -798,12 +1703,29 @@ func (r *rootPackage) parseInterfaceMethods(p *types.Package, it *gotypes.Interf
 	}
 	// Sort methods by their names.
 	sort.Slice(intf.Methods, func(i, j int) bool { return intf.Methods[i].FuncName < intf.Methods[j].FuncName })
+
+	// The explicitly declared methods are a subset of the interface method set.
+	intf.ExplicitMethods = nil
+	for i := 0; i < it.NumExplicitMethods(); i++ {
+		if m, ok := intf.Method(it.ExplicitMethod(i).Name()); ok {
+			intf.ExplicitMethods = append(intf.ExplicitMethods, *m)
+		}
+	}
+	sort.Slice(intf.ExplicitMethods, func(i, j int) bool {
+		return intf.ExplicitMethods[i].FuncName < intf.ExplicitMethods[j].FuncName
+	})
 	return true
 }
 
 func (r *rootPackage) finishNamedStructType(t *types.Struct, named *gotypes.Named) bool {
 	p := r.refPkg
-	r.parseStructFields(p, named.Underlying().(*gotypes.Struct), t)
+	var ok bool
+	if t.TypeParams, ok = r.parseTypeParams(p, named.TypeParams()); !ok {
+		return false
+	}
+	if !r.parseStructFields(p, named.Underlying().(*gotypes.Struct), t) {
+		return false
+	}
 
 	t.TypeName = named.Obj().Name()
 
@@ -833,12 +1755,13 @@ func (r *rootPackage) parseStructType(p *types.Package, ot *gotypes.Struct) (*ty
 }
 
 func (r *rootPackage) parseStructFields(p *types.Package, ot *gotypes.Struct, t *types.Struct) bool {
+	var n int
 	for i := 0; i < ot.NumFields(); i++ {
 		f := ot.Field(i)
-		ft, ok := r.dereferenceType(p, f.Type())
-		if !ok {
-			return ok
+		if !r.pkgMap.mapsName(r.loadConfig, p.Path, f.Name()) {
+			continue
 		}
+		ft := r.resolveType(p, f.Type(), r.typeExpr(f), memberReference(t.TypeName, f.Name()), r.objectPosition(f))
 		sField := types.StructField{
 			Name:      f.Name(),
 			Tag:       types.StructTag(ot.Tag(i)),
@@ -846,9 +1769,12 @@ func (r *rootPackage) parseStructFields(p *types.Package, ot *gotypes.Struct, t
 			Index:     []int{i},
 			Embedded:  f.Embedded(),
 			Anonymous: f.Anonymous(),
+			Pos:       r.objectPosition(f),
 		}
-		t.Fields[i] = sField
+		t.Fields[n] = sField
+		n++
 	}
+	t.Fields = t.Fields[:n]
 	return true
 }
 
@@ -861,10 +1787,10 @@ func (r *rootPackage) parseMethod(p *types.Package, named astMethoder, i int, ne
 
 	s, ok := m.Type().(*gotypes.Signature)
 	if !ok {
-		log.Printf("method type is not a signature: %+v\n", m.Type())
+		r.loadConfig.log(LogWarning, "method type is not a signature", "package", r.typesPkg.Path(), "method", m.Name(), "type", m.Type())
 		return types.Function{}, false
 	}
-	ft := types.Function{FuncName: m.Name(), Pkg: p}
+	ft := types.Function{FuncName: m.Name(), Pkg: p, Pos: r.objectPosition(m)}
 	if ok = r.parseSignatureType(p, s, &ft, needReceiver); !ok {
 		return types.Function{}, false
 	}
@@ -875,20 +1801,18 @@ func (r *rootPackage) parseSignatureType(p *types.Package, s *gotypes.Signature,
 	xm.Variadic = s.Variadic()
 
 	if needReceiver && s.Recv() != nil {
+		r.mapReceiverTypeParams(s)
 		xm.Receiver = &types.Receiver{Name: s.Recv().Name()}
-		xm.Receiver.Type, _ = r.dereferenceType(p, s.Recv().Type())
+		xm.Receiver.Type = r.resolveType(p, s.Recv().Type(), r.typeExpr(s.Recv()), xm.FuncName, r.objectPosition(s.Recv()))
 	}
 	if params := s.Params(); params != nil {
 		xm.In = make([]types.FuncParam, params.Len())
 		for j := 0; j < params.Len(); j++ {
 			pm := params.At(j)
-			in := types.FuncParam{Name: pm.Name()}
-			pt, ok := r.dereferenceType(p, pm.Type())
-			if !ok {
-				return false
+			xm.In[j] = types.FuncParam{
+				Name: pm.Name(),
+				Type: r.resolveType(p, pm.Type(), r.typeExpr(pm), memberReference(xm.FuncName, pm.Name()), r.objectPosition(pm)),
 			}
-			in.Type = pt
-			xm.In[j] = in
 		}
 	}
 
@@ -896,23 +1820,115 @@ func (r *rootPackage) parseSignatureType(p *types.Package, s *gotypes.Signature,
 		xm.Out = make([]types.FuncParam, results.Len())
 		for j := 0; j < results.Len(); j++ {
 			pm := results.At(j)
-			out := types.FuncParam{Name: pm.Name()}
-			pt, ok := r.dereferenceType(p, pm.Type())
-			if !ok {
-				return false
+			xm.Out[j] = types.FuncParam{
+				Name: pm.Name(),
+				Type: r.resolveType(p, pm.Type(), r.typeExpr(pm), memberReference(xm.FuncName, pm.Name()), r.objectPosition(pm)),
 			}
-			out.Type = pt
-			xm.Out[j] = out
 		}
 	}
 	return true
 }
 
+// resolveType dereferences given type. If the type could not be dereferenced it is replaced with the types.Unresolved
+// so that the declaration referencing it is still usable. The composite types are resolved by their elements,
+// so that only the unresolved element is replaced i.e.: '[]Undefined' is the slice of the unresolved type.
+// The unresolved type is reported once per reference, even if resolved again for another generic type instance.
+// The unresolved type is named by its source expression i.e.: 'Undefined', if the package syntax is loaded.
+func (r *rootPackage) resolveType(p *types.Package, tp gotypes.Type, expr ast.Expr, reference string, pos types.Position) types.Type {
+	if t, ok := r.dereferenceType(p, tp); ok {
+		return t
+	}
+	switch et := unalias(tp).(type) {
+	case *gotypes.Slice:
+		return &types.Array{ArrayKind: types.KindSlice, Type: r.resolveType(p, et.Elem(), elemExpr(expr), reference, pos)}
+	case *gotypes.Array:
+		return &types.Array{ArrayKind: types.KindArray, Type: r.resolveType(p, et.Elem(), elemExpr(expr), reference, pos), ArraySize: int(et.Len())}
+	case *gotypes.Pointer:
+		return &types.Pointer{PointedType: r.resolveType(p, et.Elem(), elemExpr(expr), reference, pos)}
+	case *gotypes.Map:
+		var keyExpr ast.Expr
+		if mt, ok := unparen(expr).(*ast.MapType); ok {
+			keyExpr = mt.Key
+		}
+		return &types.Map{Key: r.resolveType(p, et.Key(), keyExpr, reference, pos), Value: r.resolveType(p, et.Elem(), elemExpr(expr), reference, pos)}
+	case *gotypes.Chan:
+		return &types.Chan{Type: r.resolveType(p, et.Elem(), elemExpr(expr), reference, pos), Dir: types.ChanDir(et.Dir())}
+	}
+	typeString := tp.String()
+	if expr != nil {
+		typeString = gotypes.ExprString(expr)
+	}
+	if u, ok := p.GetUnresolved(typeString, reference, pos); ok {
+		return u
+	}
+	reason := unresolvedReason(tp)
+	r.pkgMap.report(Diagnostic{
+		PkgPath:  p.Path,
+		Name:     reference,
+		Pos:      pos,
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("unresolved type: %s - %s", typeString, reason),
+	})
+	return p.NewUnresolved(typeString, reason, reference, pos)
+}
+
+// elemExpr gets the element expression of the composite type expression i.e.: 'Undefined' for the '[]Undefined'.
+// The result is nil if the expression is not a composite type literal.
+func elemExpr(expr ast.Expr) ast.Expr {
+	switch et := unparen(expr).(type) {
+	case *ast.ArrayType:
+		return et.Elt
+	case *ast.Ellipsis:
+		return et.Elt
+	case *ast.StarExpr:
+		return et.X
+	case *ast.MapType:
+		return et.Value
+	case *ast.ChanType:
+		return et.Value
+	}
+	return nil
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		pe, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = pe.X
+	}
+}
+
+func unresolvedReason(tp gotypes.Type) string {
+	if strings.Contains(tp.String(), "invalid type") {
+		return "type contains an invalid type, probably an undefined identifier"
+	}
+	if _, ok := unalias(tp).(*gotypes.Named); ok {
+		return "named type not found in the loaded packages"
+	}
+	return fmt.Sprintf("unsupported type: %T", unalias(tp))
+}
+
+// memberReference gets the reference name of the declaration member i.e.: 'Foo.Field'.
+func memberReference(declName, memberName string) string {
+	if declName == "" {
+		return memberName
+	}
+	if memberName == "" || memberName == "_" {
+		return declName
+	}
+	return declName + "." + memberName
+}
+
 func (r *rootPackage) finishNamedFunc(st *gotypes.Signature, t types.Type) bool {
 	ft, ok := t.(*types.Function)
 	if !ok {
 		return false
 	}
+	if ft.TypeParams, ok = r.parseTypeParams(r.refPkg, st.TypeParams()); !ok {
+		return false
+	}
 	if !r.parseSignatureType(r.refPkg, st, ft, false) {
 		return false
 	}
@@ -921,29 +1937,22 @@ func (r *rootPackage) finishNamedFunc(st *gotypes.Signature, t types.Type) bool
 
 var errOutsideGoPath = errors.New("source directory is outside GOPATH")
 
-func parsePackageImport(srcDir string) (string, error) {
-	moduleMode := os.Getenv("GO111MODULE")
+// parsePackageImport gets the import path of the package in given directory. In the module mode the directory
+// is resolved within the go.work workspace, the local replace directives and the vendor directories.
+func parsePackageImport(srcDir string, getenv func(string) string) (string, error) {
+	moduleMode := getenv("GO111MODULE")
 	// trying to find the module
 	if moduleMode != "off" {
-		currentDir := srcDir
-		for {
-			dat, err := ioutil.ReadFile(filepath.Join(currentDir, "go.mod"))
-			if os.IsNotExist(err) {
-				if currentDir == filepath.Dir(currentDir) {
-					// at the root
-					break
-				}
-				currentDir = filepath.Dir(currentDir)
-				continue
-			} else if err != nil {
-				return "", err
-			}
-			modulePath := modfile.ModulePath(dat)
-			return filepath.ToSlash(filepath.Join(modulePath, strings.TrimPrefix(srcDir, currentDir))), nil
+		importPath, ok, err := moduleImportPath(srcDir, getenv)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return importPath, nil
 		}
 	}
 	// fall back to GOPATH mode
-	goPaths := os.Getenv("GOPATH")
+	goPaths := getenv("GOPATH")
 	if goPaths == "" {
 		return "", fmt.Errorf("GOPATH is not set")
 	}
@@ -1,7 +1,24 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
 
 	"github.com/kucjac/gentools/types"
 )
@@ -9,7 +26,7 @@ import (
 func TestParsePackages(t *testing.T) {
 	const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
 	// On test purpose try to parse this package.
-	pkgs, err := LoadPackages(LoadConfig{
+	pkgs, diagnostics, err := LoadPackages(LoadConfig{
 		Paths:    []string{"."},
 		PkgNames: []string{testCasesPkg},
 		Verbose:  true,
@@ -18,6 +35,9 @@ func TestParsePackages(t *testing.T) {
 		t.Errorf("Parsing packages failed: %v", err)
 		return
 	}
+	if diagnostics.HasErrors() {
+		t.Errorf("Parsing packages resulted with errors:\n%s", diagnostics.Errors())
+	}
 
 	// Get package reflection by it's identifier.
 	typesPkg, ok := pkgs.PackageByPath("github.com/kucjac/gentools/types")
@@ -60,8 +80,8 @@ func TestParsePackages(t *testing.T) {
 	}
 
 	// The API allows to check the fields for given struct type.
-	if len(structType.Fields) != 5 {
-		t.Errorf("'Struct' should have 5 fields but have: %d", len(structType.Fields))
+	if len(structType.Fields) != 12 {
+		t.Errorf("'Struct' should have 12 fields but have: %d", len(structType.Fields))
 		return
 	}
 	for i, sField := range structType.Fields {
@@ -96,6 +116,39 @@ func TestParsePackages(t *testing.T) {
 			expectedType = "[]Function"
 			expectedKind = types.KindSlice
 			expectedElemKind = types.KindStruct
+		case 5:
+			expectedName = "TypeParams"
+			expectedType = "TypeParams"
+			expectedKind = types.KindSlice
+			expectedElemKind = types.KindSlice
+		case 6:
+			expectedName = "TypeArgs"
+			expectedType = "[]Type"
+			expectedKind = types.KindSlice
+			expectedElemKind = types.KindInterface
+		case 7:
+			expectedName = "Origin"
+			expectedType = "*Struct"
+			expectedKind = types.KindPtr
+			expectedElemKind = types.KindStruct
+		case 8:
+			expectedName = "Pos"
+			expectedType = "Position"
+			expectedKind = types.KindStruct
+		case 9:
+			expectedName = "Builds"
+			expectedType = "BuildContexts"
+			expectedKind = types.KindSlice
+			expectedElemKind = types.KindSlice
+		case 10:
+			expectedName = "Markers"
+			expectedType = "Markers"
+			expectedKind = types.KindSlice
+			expectedElemKind = types.KindSlice
+		case 11:
+			expectedName = "BuildConstraint"
+			expectedType = "string"
+			expectedKind = types.KindString
 		}
 		if sField.Name != expectedName {
 			t.Errorf("Expected field name mismatch. Expected: %s, is %s", expectedName, sField.Name)
@@ -165,3 +218,2102 @@ func TestParsePackages(t *testing.T) {
 		t.Errorf("'embeddedType' field type with 'testing' package context should be '*T' but is: %v", etField.Type.Name(true, ""))
 	}
 }
+
+func TestLoadPackagesBestEffort(t *testing.T) {
+	const brokenPkg = "github.com/kucjac/gentools/parser/testdata/broken"
+	t.Run("Strict", func(t *testing.T) {
+		_, diagnostics, err := LoadPackages(LoadConfig{Paths: []string{"./testdata/broken"}})
+		if !errors.Is(err, ErrPackageErrors) {
+			t.Fatalf("expected ErrPackageErrors but got: %v", err)
+		}
+		if !diagnostics.HasErrors() {
+			t.Fatal("expected error diagnostics")
+		}
+	})
+
+	t.Run("BestEffort", func(t *testing.T) {
+		pkgs, diagnostics, err := LoadPackages(LoadConfig{Paths: []string{"./testdata/broken"}, BestEffort: true})
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		errs := diagnostics.Errors()
+		if len(errs) == 0 {
+			t.Fatal("expected error diagnostics")
+		}
+		d := errs[0]
+		if d.PkgPath != brokenPkg {
+			t.Errorf("expected diagnostic package path: %s, got: %s", brokenPkg, d.PkgPath)
+		}
+		if !strings.HasSuffix(d.Pos.Filename, "broken.go") || d.Pos.Line != 13 {
+			t.Errorf("unexpected diagnostic position: %s", d.Pos)
+		}
+
+		pkg, ok := pkgs.PackageByPath(brokenPkg)
+		if !ok {
+			t.Fatal("broken package not found")
+		}
+		if _, ok = pkg.GetType("Valid"); !ok {
+			t.Error("valid type not found in the partially loaded package")
+		}
+		if _, ok = pkg.Declarations["Counter"]; !ok {
+			t.Error("valid constant not found in the partially loaded package")
+		}
+
+		invalid, ok := pkg.GetType("Invalid")
+		if !ok {
+			t.Fatal("invalid type not found")
+		}
+		st, ok := invalid.(*types.Struct)
+		if !ok {
+			t.Fatalf("invalid type is expected to be a struct but is: %T", invalid)
+		}
+		if len(st.Fields) != 2 {
+			t.Fatalf("invalid struct is expected to have 2 fields but has: %d", len(st.Fields))
+		}
+		if st.Fields[0].Type != types.Int {
+			t.Errorf("invalid struct ID field is expected to be int but is: %s", st.Fields[0].Type)
+		}
+		unresolved, ok := st.Fields[1].Type.(*types.Unresolved)
+		if !ok {
+			t.Fatalf("invalid struct Unknown field is expected to be unresolved but is: %T", st.Fields[1].Type)
+		}
+		if unresolved.Kind() != types.KindUnresolved || unresolved.Reason == "" || unresolved.TypeString != "Undefined" {
+			t.Errorf("unexpected unresolved type: %+v", unresolved)
+		}
+
+		// The distinct unresolved types of a single reference are not shared.
+		mapped := pkg.MustStruct("Mapped")
+		if mt, ok := mapped.Fields[0].Type.(*types.Map); !ok {
+			t.Errorf("Mapped.M field is expected to be a map but is: %T", mapped.Fields[0].Type)
+		} else if mt.Key.String() != "UndefinedKey" || mt.Value.String() != "UndefinedValue" {
+			t.Errorf("Mapped.M field is expected to be a map of the unresolved types, got: %s", mt)
+		}
+
+		process, ok := pkg.GetFunction("Process")
+		if !ok {
+			t.Fatal("function Process not found")
+		}
+		if len(process.In) != 2 || process.In[0].Type.Kind() != types.KindSlice || process.In[1].Type != types.Int {
+			t.Errorf("unexpected Process function signature: %s", process)
+		} else if elem := process.In[0].Type.Elem(); elem == nil || elem.Kind() != types.KindUnresolved {
+			t.Errorf("Process in parameter is expected to be a slice of the unresolved type: %s", process.In[0].Type)
+		}
+
+		wrapper, ok := pkg.GetType("Wrapper")
+		if !ok {
+			t.Fatal("type Wrapper not found")
+		}
+		if wt, ok := wrapper.(*types.Alias); !ok || wt.Type == nil || wt.Type.Kind() != types.KindUnresolved {
+			t.Errorf("wrapper is expected to be an alias of the unresolved type: %#v", wrapper)
+		}
+
+		refs := pkgs.Unresolved()
+		// The unresolved field of the generic struct is reported once regardless of its instances.
+		var boxWarnings int
+		for _, d := range diagnostics {
+			if d.Name == "Box.Missing" && strings.HasPrefix(d.Message, "unresolved type") {
+				boxWarnings++
+			}
+		}
+		if boxWarnings != 1 {
+			t.Errorf("expected single unresolved type warning for Box.Missing, got: %d", boxWarnings)
+		}
+		expected := []string{"Invalid.Unknown", "Wrapper", "Process.in", "Box.Missing", "Mapped.M", "Mapped.M"}
+		if len(refs) != len(expected) {
+			t.Fatalf("expected %d unresolved references, got: %d", len(expected), len(refs))
+		}
+		for i, ref := range refs {
+			if ref.Reference != expected[i] {
+				t.Errorf("expected unresolved reference: %s, got: %s", expected[i], ref.Reference)
+			}
+			if !ref.Pos.IsValid() {
+				t.Errorf("unresolved reference: %s position is not valid", ref.Reference)
+			}
+		}
+	})
+}
+
+func TestLoadPackagesRecoveredPanic(t *testing.T) {
+	const importedPkg = "github.com/kucjac/gentools/parser/testcases/imported"
+	for _, bestEffort := range []bool{false, true} {
+		cfg := &LoadConfig{BestEffort: bestEffort}
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), cfg, nil, importedPkg)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		// The type specification without the name makes the package parsing panic.
+		pkgs[0].Syntax = append(pkgs[0].Syntax, &ast.File{Decls: []ast.Decl{
+			&ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&ast.TypeSpec{Type: &ast.Ident{Name: "int"}}}},
+		}})
+		err = p.parsePackages(context.Background(), cfg, pkgs...)
+		if !p.diagnostics.HasErrors() {
+			t.Errorf("expected the recovered panic to be reported as an error, got: %v", p.diagnostics)
+		}
+		_, parsed := p.read(importedPkg)
+		switch {
+		case bestEffort && (err != nil || !parsed):
+			t.Errorf("expected the partially parsed package in the best effort mode, got: %v", err)
+		case !bestEffort && (!errors.Is(err, ErrPackageErrors) || parsed):
+			t.Errorf("expected ErrPackageErrors without the partially parsed package, got: %v", err)
+		}
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected types.Position
+	}{
+		{in: "/a/b.go:12:5", expected: types.Position{Filename: "/a/b.go", Line: 12, Column: 5}},
+		{in: "/a/b.go:12", expected: types.Position{Filename: "/a/b.go", Line: 12}},
+		{in: "/a/b.go"},
+		{in: ""},
+	}
+	for _, tc := range tests {
+		if p := parseErrorPosition(tc.in); p != tc.expected {
+			t.Errorf("parsing position: '%s' expected: %+v, got: %+v", tc.in, tc.expected, p)
+		}
+	}
+}
+
+func TestLoadPackagesContext(t *testing.T) {
+	const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+	t.Run("CanceledLoad", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := LoadPackagesContext(ctx, LoadConfig{PkgNames: []string{testCasesPkg}})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled error, got: %v", err)
+		}
+	})
+
+	t.Run("CanceledParse", func(t *testing.T) {
+		cfg := &LoadConfig{PkgNames: []string{testCasesPkg}}
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), cfg, nil, cfg.PkgNames...)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err = p.parsePackages(ctx, cfg, pkgs...); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled error, got: %v", err)
+		}
+		if len(p.pkgMap) != 0 {
+			t.Errorf("expected no packages after canceled parsing, got: %d", len(p.pkgMap))
+		}
+	})
+
+	t.Run("CanceledDeclarations", func(t *testing.T) {
+		cfg := &LoadConfig{Overlay: map[string][]byte{
+			"testdata/overlay/overlay.go": []byte("package overlay\n\nconst Version = \"1.0\"\n\nvar Count int\n"),
+		}}
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), cfg, nil, "./testdata/overlay")
+		if err != nil || len(pkgs) != 1 || pkgs[0].Types == nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := pkgs[0].Types.Scope()
+		r := &rootPackage{
+			ctx:        ctx,
+			pkgPkg:     pkgs[0],
+			typesPkg:   pkgs[0].Types,
+			pkgMap:     p,
+			loadConfig: cfg,
+			refPkg:     p.newPackage(pkgs[0].PkgPath, pkgs[0].Name),
+			declNames:  s.Names(),
+		}
+		// The declarations are not defined once the parsing is canceled, even in the middle of the stage.
+		r.defineDeclarations(s, r.refPkg)
+		if len(r.refPkg.Declarations) != 0 {
+			t.Errorf("expected no declarations after canceled parsing, got: %d", len(r.refPkg.Declarations))
+		}
+	})
+
+	t.Run("CanceledUpdate", func(t *testing.T) {
+		pkgs := types.PackageMap{}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		if _, err := UpdatePackagesContext(ctx, pkgs, LoadConfig{PkgNames: []string{testCasesPkg}}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded error, got: %v", err)
+		}
+		if len(pkgs) != 0 {
+			t.Errorf("expected packages map to be unchanged, got: %d packages", len(pkgs))
+		}
+	})
+}
+
+func TestLoadPackagesOverlay(t *testing.T) {
+	const overlayPkg = "github.com/kucjac/gentools/parser/testdata/overlay"
+	pkgs, _, err := LoadPackages(LoadConfig{
+		Paths:        []string{"./testdata/overlay"},
+		WithComments: true,
+		Overlay: map[string][]byte{
+			"testdata/overlay/overlay.go": []byte(`package overlay
+
+// Model is the type defined only in memory.
+type Model struct {
+	// ID is the model identifier.
+	ID int
+}
+
+// ModelID is the type wrapper defined only in memory.
+type ModelID int
+`),
+		},
+	})
+	if err != nil {
+		t.Fatalf("loading overlay packages failed: %v", err)
+	}
+	pkg, ok := pkgs.PackageByPath(overlayPkg)
+	if !ok {
+		t.Fatal("overlay package not found")
+	}
+	model, ok := pkg.GetType("Model")
+	if !ok {
+		t.Fatal("type Model not found")
+	}
+	st, ok := model.(*types.Struct)
+	if !ok {
+		t.Fatalf("type Model is expected to be a struct but is: %T", model)
+	}
+	if st.Comment != "Model is the type defined only in memory.\n" {
+		t.Errorf("unexpected Model comment: %q", st.Comment)
+	}
+	if len(st.Fields) != 1 || st.Fields[0].Comment != "ID is the model identifier.\n" {
+		t.Errorf("unexpected Model fields: %v", st.Fields)
+	}
+	modelID, ok := pkg.GetType("ModelID")
+	if !ok {
+		t.Fatal("type ModelID not found")
+	}
+	if alias, ok := modelID.(*types.Alias); !ok || alias.Type != types.Int {
+		t.Errorf("type ModelID is expected to be an int wrapper: %v", modelID)
+	}
+	// The overlay file doesn't exist on disk.
+	src, err := st.Pos.Source()
+	if err != nil {
+		t.Fatalf("reading Model source failed: %v", err)
+	}
+	if !strings.HasPrefix(string(src), "type Model struct {") {
+		t.Errorf("unexpected Model source: %q", src)
+	}
+}
+
+func TestLoadPackagesBuildContexts(t *testing.T) {
+	const multiPkg = "github.com/kucjac/gentools/parser/testdata/multibuild"
+	linux := types.BuildContext{GOOS: "linux", GOARCH: "amd64"}
+	windows := types.BuildContext{GOOS: "windows", GOARCH: "amd64"}
+	custom := types.BuildContext{GOOS: "linux", GOARCH: "amd64", Tags: []string{"custom"}}
+	var (
+		mu      sync.Mutex
+		started = map[string]int{}
+	)
+	pkgs, diagnostics, err := LoadPackages(LoadConfig{
+		Paths:         []string{"./testdata/multibuild"},
+		WithComments:  true,
+		BuildContexts: []types.BuildContext{linux, windows, custom},
+		Progress: func(event Progress) {
+			if event.Kind == PackageStarted && event.Phase == PhaseParse {
+				mu.Lock()
+				started[event.PkgPath]++
+				mu.Unlock()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if diagnostics.HasErrors() {
+		t.Fatalf("loading packages resulted with errors:\n%s", diagnostics)
+	}
+	pkg, ok := pkgs.PackageByPath(multiPkg)
+	if !ok {
+		t.Fatal("package multibuild not found")
+	}
+
+	common := pkg.MustStruct("Common")
+	if len(common.Builds) != 3 || common.BuildConstraint != "" {
+		t.Errorf("expected Common to be declared in all build contexts, got: %v %q", common.Builds, common.BuildConstraint)
+	}
+	expectedMethods := map[string]types.BuildContexts{
+		"Linux":   {linux, custom},
+		"Name":    {linux, windows, custom},
+		"Windows": {windows},
+	}
+	expectedConstraints := map[string]string{"Linux": "linux", "Name": "", "Windows": "windows"}
+	if len(common.Methods) != len(expectedMethods) {
+		t.Fatalf("expected %d Common methods, got: %d", len(expectedMethods), len(common.Methods))
+	}
+	for _, method := range common.Methods {
+		expected := expectedMethods[method.FuncName]
+		if len(method.Builds) != len(expected) {
+			t.Errorf("method %s expected build contexts: %v, got: %v", method.FuncName, expected, method.Builds)
+			continue
+		}
+		for _, bc := range expected {
+			if !method.Builds.Contains(bc) {
+				t.Errorf("method %s expected to be declared under: %s", method.FuncName, bc)
+			}
+		}
+		if method.BuildConstraint != expectedConstraints[method.FuncName] {
+			t.Errorf("method %s expected build constraint %q, got: %q", method.FuncName, expectedConstraints[method.FuncName], method.BuildConstraint)
+		}
+	}
+
+	linuxOnly := pkg.MustStruct("LinuxOnly")
+	if len(linuxOnly.Builds) != 2 || !linuxOnly.Builds.Contains(linux) || !linuxOnly.Builds.Contains(custom) {
+		t.Errorf("unexpected LinuxOnly build contexts: %v", linuxOnly.Builds)
+	}
+	if linuxOnly.BuildConstraint != "linux" {
+		t.Errorf("unexpected LinuxOnly build constraint: %q", linuxOnly.BuildConstraint)
+	}
+
+	windowsOnly := pkg.MustStruct("WindowsOnly")
+	if len(windowsOnly.Builds) != 1 || !windowsOnly.Builds.Contains(windows) {
+		t.Errorf("unexpected WindowsOnly build contexts: %v", windowsOnly.Builds)
+	}
+	if windowsOnly.Comment != "WindowsOnly is the type declared only on windows.\n" {
+		t.Errorf("unexpected WindowsOnly comment: %q", windowsOnly.Comment)
+	}
+	// The types merged from different build contexts should refer to the same type.
+	if ptr, ok := windowsOnly.Fields[0].Type.(*types.Pointer); !ok || ptr.PointedType != common {
+		t.Errorf("WindowsOnly.Common field is expected to point to the Common type")
+	}
+
+	decl, ok := pkg.Declarations["WindowsVersion"]
+	if !ok {
+		t.Fatal("declaration WindowsVersion not found")
+	}
+	if len(decl.Builds) != 1 || !decl.Builds.Contains(windows) {
+		t.Errorf("unexpected WindowsVersion build contexts: %v", decl.Builds)
+	}
+	if decl.BuildConstraint != "windows" {
+		t.Errorf("unexpected WindowsVersion build constraint: %q", decl.BuildConstraint)
+	}
+
+	// The type declared in the files of different build contexts is constrained by any of them.
+	handle, ok := pkg.GetType("Handle")
+	if !ok {
+		t.Fatal("type Handle not found")
+	}
+	if alias, ok := handle.(*types.Alias); !ok || len(alias.Builds) != 3 || alias.BuildConstraint != "linux || windows" {
+		t.Errorf("unexpected Handle build annotations: %v", handle)
+	}
+
+	tagged := pkg.MustFunction("Tagged")
+	if len(tagged.Builds) != 1 || !tagged.Builds.Contains(custom) || tagged.BuildConstraint != "custom" {
+		t.Errorf("unexpected Tagged build contexts: %v %q", tagged.Builds, tagged.BuildConstraint)
+	}
+
+	// The dependency with the same files under all build contexts is parsed once, and annotated with all of them.
+	if n := started[multiPkg+"/shared"]; n != 1 {
+		t.Errorf("expected the shared package to be parsed once, got: %d", n)
+	}
+	sharedPkg, ok := pkgs.PackageByPath(multiPkg + "/shared")
+	if !ok {
+		t.Fatal("package shared not found")
+	}
+	if shared := sharedPkg.MustStruct("Shared"); len(shared.Builds) != 3 {
+		t.Errorf("expected Shared to be annotated with all build contexts, got: %v", shared.Builds)
+	}
+
+	// The build constraints are read from the file headers also if the package syntax is not loaded.
+	pkgs, _, err = LoadPackages(LoadConfig{
+		Paths:         []string{"./testdata/multibuild"},
+		BuildContexts: []types.BuildContext{linux, custom},
+	})
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if pkg, ok = pkgs.PackageByPath(multiPkg); !ok {
+		t.Fatal("package multibuild not found")
+	}
+	if tagged = pkg.MustFunction("Tagged"); tagged.BuildConstraint != "custom" {
+		t.Errorf("unexpected Tagged build constraint without the syntax: %q", tagged.BuildConstraint)
+	}
+}
+
+func TestLoadPackagesTests(t *testing.T) {
+	const testsPkg = "github.com/kucjac/gentools/parser/testdata/withtests"
+	pkgs, diagnostics, err := LoadPackages(LoadConfig{Paths: []string{"./testdata/withtests"}, Tests: true})
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if diagnostics.HasErrors() {
+		t.Fatalf("loading packages resulted with errors:\n%s", diagnostics)
+	}
+
+	pkg, ok := pkgs.PackageByPath(testsPkg)
+	if !ok {
+		t.Fatal("package withtests not found")
+	}
+	if pkg.ForTest != testsPkg || pkg.IsExternalTest() {
+		t.Errorf("package withtests is expected to be loaded with its tests: %s", pkg.ForTest)
+	}
+	if _, ok = pkg.GetType("fixture"); !ok {
+		t.Error("test file type 'fixture' not found")
+	}
+	if _, ok = pkg.GetFunction("TestGreet"); !ok {
+		t.Error("test function 'TestGreet' not found")
+	}
+
+	ext, ok := pkgs.PackageByPath(testsPkg + "_test")
+	if !ok {
+		t.Fatal("external test package not found")
+	}
+	if !ext.IsExternalTest() || ext.ForTest != testsPkg {
+		t.Errorf("package withtests_test is expected to be an external test package: %s", ext.ForTest)
+	}
+	if len(ext.Examples) != 2 {
+		t.Fatalf("expected 2 examples, got: %d", len(ext.Examples))
+	}
+	for _, example := range ext.Examples {
+		switch example.Name {
+		case "Greet":
+			if example.Output != "hello\n" || example.Unordered {
+				t.Errorf("unexpected Greet example output: %q", example.Output)
+			}
+			if example.Func == nil || example.Func.FuncName != "ExampleGreet" {
+				t.Errorf("unexpected Greet example function: %v", example.Func)
+			}
+			if example.Comment != "ExampleGreet shows how to greet.\n" {
+				t.Errorf("unexpected Greet example comment: %q", example.Comment)
+			}
+		case "_unordered":
+			if example.Output != "b\na\n" || !example.Unordered {
+				t.Errorf("unexpected unordered example output: %q", example.Output)
+			}
+		default:
+			t.Errorf("unexpected example: %s", example.Name)
+		}
+	}
+}
+
+func TestLoadPackagesLazyDependencies(t *testing.T) {
+	const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+	t.Run("Mapping", func(t *testing.T) {
+		// The dependencies are loaded in full, so that only the lazy mapping is tested regardless of
+		// the export data support.
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), &LoadConfig{WithComments: true}, nil, testCasesPkg)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		if err = p.parsePackages(context.Background(), &LoadConfig{WithComments: true, LazyDependencies: true}, pkgs...); err != nil {
+			t.Fatalf("parsing packages failed: %v", err)
+		}
+		if p.diagnostics.HasErrors() {
+			t.Fatalf("parsing packages resulted with errors:\n%s", p.diagnostics)
+		}
+		testLazyDependencies(t, p.pkgMap, testCasesPkg)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), &LoadConfig{}, nil, testCasesPkg)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		if err = p.parsePackages(context.Background(), &LoadConfig{LazyDependencies: true}, pkgs...); err != nil {
+			t.Fatalf("parsing packages failed: %v", err)
+		}
+		testingPkg, ok := p.read("testing")
+		if !ok {
+			t.Fatal("dependency package testing not found")
+		}
+
+		// All the concurrent callers should get the finished type, regardless of which one maps it.
+		// The type is checked right after it is returned, as the type in progress is finished later on.
+		type result struct {
+			st      *types.Struct
+			fields  bool
+			methods bool
+		}
+		const n = 8
+		// The callers are run in parallel even on a single CPU.
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+		results := make([]result, n)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				st, ok := testingPkg.GetStruct("B")
+				if !ok {
+					return
+				}
+				res := result{st: st, fields: len(st.Fields) != 0}
+				for _, f := range st.Fields {
+					if f.Type == nil {
+						res.fields = false
+					}
+				}
+				for _, m := range st.Methods {
+					if m.FuncName == "Run" {
+						res.methods = true
+					}
+				}
+				results[i] = res
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		for i, res := range results {
+			if res.st == nil {
+				t.Fatalf("testing.B not resolved by the caller %d", i)
+			}
+			if res.st != results[0].st {
+				t.Errorf("testing.B resolved by the caller %d is a different type", i)
+			}
+			if !res.fields {
+				t.Errorf("testing.B resolved by the caller %d has unfinished fields", i)
+			}
+			if !res.methods {
+				t.Errorf("testing.B resolved by the caller %d has no Run method", i)
+			}
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		p := &packageMap{pkgMap: types.PackageMap{}}
+		pkgs, err := p.loadPackages(context.Background(), &LoadConfig{}, nil, testCasesPkg)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		var late Diagnostics
+		cfg := &LoadConfig{LazyDependencies: true, LazyDiagnostics: func(d Diagnostic) { late = append(late, d) }}
+		if err = p.parsePackages(context.Background(), cfg, pkgs...); err != nil {
+			t.Fatalf("parsing packages failed: %v", err)
+		}
+		if p.lazyTypes == 0 {
+			t.Fatal("expected the dependency types referenced by the package to be mapped")
+		}
+		// No more types could be mapped once the limit is reached, and the diagnostics reported after the load
+		// are passed to the callback.
+		p.finishDiagnostics()
+		cfg.LazyTypesLimit = p.lazyTypes
+		timePkg, ok := p.read("time")
+		if !ok {
+			t.Fatal("dependency package time not found")
+		}
+		if _, ok = timePkg.GetType("Ticker"); ok {
+			t.Error("time.Ticker should not be mapped beyond the lazy types limit")
+		}
+		if len(late) != 1 || late[0].Name != "Ticker" || !strings.Contains(late[0].Message, "limit") {
+			t.Errorf("expected the late diagnostic of the lazy types limit, got: %v", late)
+		}
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		pkgs, diagnostics, err := LoadPackages(LoadConfig{PkgNames: []string{testCasesPkg}, LazyDependencies: true})
+		if errors.Is(err, ErrPackageErrors) && strings.Contains(diagnostics.String(), "export data") {
+			t.Skipf("the toolchain export data is not supported by the packages loader: %s", diagnostics)
+		}
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		testLazyDependencies(t, pkgs, testCasesPkg)
+	})
+}
+
+func TestParseNamedInstance(t *testing.T) {
+	const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+	p := &packageMap{pkgMap: types.PackageMap{}}
+	cfg := &LoadConfig{}
+	pkgs, err := p.loadPackages(context.Background(), cfg, nil, testCasesPkg)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if err = p.parsePackages(context.Background(), cfg, pkgs...); err != nil {
+		t.Fatalf("parsing packages failed: %v", err)
+	}
+	root := &rootPackage{pkgMap: p, loadConfig: cfg, typesPkg: pkgs[0].Types, pkgPkg: pkgs[0]}
+	generics := pkgs[0].Types.Scope().Lookup("Generics").Type().Underlying().(*gotypes.Struct)
+	named := generics.Field(0).Type().(*gotypes.Named)
+	published, ok := p.readInstance(named)
+	if !ok {
+		t.Fatal("instance List[int] not found")
+	}
+	if st, ok := published.(*types.Struct); !ok || len(st.Fields) != 2 || st.Fields[1].Type != types.Int {
+		t.Fatalf("instance List[int] is expected to be finished: %v", published)
+	}
+
+	// The instances in progress are not visible in the package map until published.
+	p.instances = nil
+	staged := &types.Struct{TypeName: "List"}
+	root.stageInstance(named, staged)
+	if _, ok = p.readInstance(named); ok {
+		t.Error("instance in progress should not be set in the package map")
+	}
+	if tp, ok := root.readInstance(named); !ok || tp != staged {
+		t.Error("instance in progress should be resolved by its root")
+	}
+	root.publishInstances()
+	if tp, ok := p.readInstance(named); !ok || tp != staged {
+		t.Error("finished instance should be set in the package map")
+	}
+
+	// The instance that failed to parse is dropped.
+	p.instances = nil
+	root.stageInstance(named, staged)
+	root.dropInstance(named)
+	root.publishInstances()
+	if _, ok = p.readInstance(named); ok {
+		t.Error("failed instance should not be set in the package map")
+	}
+}
+
+func testLazyDependencies(t *testing.T, pkgs types.PackageMap, rootPkg string) {
+	pkg, ok := pkgs.PackageByPath(rootPkg)
+	if !ok {
+		t.Fatal("root package not found")
+	}
+	if pkg.IsLazy() {
+		t.Error("requested package should not be lazy")
+	}
+	foo := pkg.MustStruct("Foo")
+	if len(foo.Fields) == 0 {
+		t.Errorf("requested package types should be parsed in full: %v", foo)
+	}
+
+	timePkg, ok := pkgs.PackageByPath("time")
+	if !ok {
+		t.Fatal("dependency package time not found")
+	}
+	if !timePkg.IsLazy() {
+		t.Error("dependency package should be lazy")
+	}
+	timePkg.Lock()
+	_, mapped := timePkg.Types["Ticker"]
+	timePkg.Unlock()
+	if mapped {
+		t.Error("not referenced dependency type should not be mapped")
+	}
+
+	ticker, ok := timePkg.GetStruct("Ticker")
+	if !ok {
+		t.Fatal("time.Ticker not resolved")
+	}
+	if len(ticker.Fields) == 0 || ticker.Fields[0].Name != "C" {
+		t.Fatalf("time.Ticker C field not found: %v", ticker.Fields)
+	}
+	if ch, ok := ticker.Fields[0].Type.(*types.Chan); !ok || ch.Type != timePkg.MustGetType("Time") {
+		t.Errorf("time.Ticker C field is expected to be a channel of time.Time: %s", ticker.Fields[0].Type)
+	}
+	var hasStop bool
+	for _, m := range ticker.Methods {
+		if m.FuncName == "Stop" {
+			hasStop = true
+		}
+	}
+	if !hasStop {
+		t.Error("time.Ticker Stop method not found")
+	}
+
+	month, ok := pkgs.TypeOf("time.Month", nil)
+	if !ok {
+		t.Fatal("time.Month not resolved by the package map")
+	}
+	if month.Kind() != types.KindInt {
+		t.Errorf("time.Month is expected to be an int wrapper: %s", month.Kind())
+	}
+}
+
+func TestLoadPackagesCache(t *testing.T) {
+	const (
+		testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+		importedPkg  = "github.com/kucjac/gentools/parser/testcases/imported"
+	)
+	cacheDir := t.TempDir()
+	cfg := LoadConfig{PkgNames: []string{testCasesPkg}, WithComments: true, CacheDir: cacheDir}
+	parsed, _, err := LoadPackages(cfg)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(parsed) {
+		t.Errorf("expected %d cached packages but got: %d", len(parsed), len(entries))
+	}
+
+	t.Run("Restore", func(t *testing.T) {
+		if toParse := testCachedPackagesToParse(t, cfg, testCasesPkg); len(toParse) != 0 {
+			t.Errorf("all packages are expected to be restored, but these needs to be parsed: %v", toParse)
+		}
+
+		restored, _, err := LoadPackages(cfg)
+		if err != nil {
+			t.Fatalf("loading cached packages failed: %v", err)
+		}
+		if len(restored) != len(parsed) {
+			t.Fatalf("expected %d restored packages but got: %d", len(parsed), len(restored))
+		}
+		for path, pkg := range parsed {
+			var expected, actual bytes.Buffer
+			if err = types.EncodePackage(&expected, pkg); err != nil {
+				t.Fatalf("encoding package %s failed: %v", path, err)
+			}
+			if err = types.EncodePackage(&actual, restored[path]); err != nil {
+				t.Fatalf("encoding package %s failed: %v", path, err)
+			}
+			if expected.String() != actual.String() {
+				t.Errorf("restored package %s doesn't match the parsed one", path)
+			}
+		}
+
+		foo := restored[testCasesPkg].MustStruct("Foo")
+		for _, field := range foo.Fields {
+			if pkgr, ok := field.Type.(types.Packager); ok && pkgr.Package() != nil && !pkgr.Package().IsStandard() {
+				if pkg := restored[pkgr.Package().Path]; pkg != pkgr.Package() {
+					t.Errorf("field %s type package is expected to be the restored package", field.Name)
+				}
+			}
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		changed := cfg
+		changed.Overlay = map[string][]byte{
+			"testcases/imported/changed.go": []byte("package imported\n\n// Changed is the type defined only in memory.\ntype Changed struct{}\n"),
+		}
+		paths := testCachedPackagesToParse(t, changed, testCasesPkg)
+		if len(paths) != 2 || paths[0] != testCasesPkg || paths[1] != importedPkg {
+			t.Errorf("only the changed package and its dependents are expected to be parsed, but got: %v", paths)
+		}
+
+		pkgs, _, err := LoadPackages(changed)
+		if err != nil {
+			t.Fatalf("loading changed packages failed: %v", err)
+		}
+		if _, ok := pkgs[importedPkg].GetStruct("Changed"); !ok {
+			t.Error("changed package is expected to be parsed again")
+		}
+	})
+}
+
+// testCachedPackagesToParse restores the cached packages, and gets the sorted paths of the packages
+// that needs to be parsed.
+func testCachedPackagesToParse(t *testing.T, cfg LoadConfig, pkgNames ...string) []string {
+	t.Helper()
+	p := &packageMap{pkgMap: types.PackageMap{}}
+	pkgs, err := p.loadPackages(context.Background(), &cfg, nil, pkgNames...)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	cache, err := newPackageCache(&cfg, nil, &packageMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := map[string]*importedPackage{}
+	for _, pkg := range pkgs {
+		getAllImports(pkg, imports, func(string) bool { return false })
+	}
+	var pkgList []*importedPackage
+	for _, imp := range imports {
+		pkgList = append(pkgList, imp)
+	}
+	var paths []string
+	for _, imp := range p.restoreCachedPackages(cache, pkgList) {
+		paths = append(paths, imp.typesPkg.Path())
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestReloadPackages(t *testing.T) {
+	const (
+		basePkg = "github.com/kucjac/gentools/parser/testdata/reload/base"
+		appPkg  = "github.com/kucjac/gentools/parser/testdata/reload/app"
+	)
+	pkgs, _, err := LoadPackages(LoadConfig{PkgNames: []string{appPkg}})
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	prevBase, prevApp, prevTime := pkgs[basePkg], pkgs[appPkg], pkgs["time"]
+
+	t.Run("Failed", func(t *testing.T) {
+		_, _, err := ReloadPackages(pkgs, LoadConfig{
+			PkgNames: []string{basePkg},
+			Overlay:  map[string][]byte{"testdata/reload/base/base.go": []byte("package base\n\ntype Model struct {")},
+		})
+		if !errors.Is(err, ErrPackageErrors) {
+			t.Fatalf("expected package errors but got: %v", err)
+		}
+		if pkgs[basePkg] != prevBase || pkgs[appPkg] != prevApp {
+			t.Error("failed reload is expected to leave the packages unchanged")
+		}
+	})
+
+	changes, _, err := ReloadPackages(pkgs, LoadConfig{
+		PkgNames: []string{basePkg},
+		Overlay: map[string][]byte{"testdata/reload/base/base.go": []byte(`package base
+
+import (
+	"time"
+)
+
+// Model is the model reloaded by the tests.
+type Model struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+}
+
+// Added is the type added by the tests.
+type Added struct{}
+
+// Unchanged is the type not changed by the tests.
+type Unchanged string
+`)},
+	})
+	if err != nil {
+		t.Fatalf("reloading packages failed: %v", err)
+	}
+	expected := Changes{
+		{PkgPath: basePkg, Name: "Added", Kind: ChangeAdded},
+		{PkgPath: basePkg, Name: "Model", Kind: ChangeModified},
+		{PkgPath: basePkg, Name: "Removed", Kind: ChangeRemoved},
+	}
+	if changes.String() != expected.String() {
+		t.Errorf("expected changes:\n%s\nbut got:\n%s", expected, changes)
+	}
+	if len(changes) == 3 {
+		members := changes[1].Members
+		if len(members) != 1 || members[0] != (MemberChange{Name: "Name", Kind: ChangeAdded}) {
+			t.Errorf("expected the Model to gain the Name field, but got: %v", members)
+		}
+	}
+
+	if pkgs[basePkg] == prevBase || pkgs[appPkg] == prevApp {
+		t.Fatal("reloaded package and its dependents are expected to be replaced")
+	}
+	if pkgs["time"] != prevTime {
+		t.Error("not reloaded dependency is expected to be kept")
+	}
+	model := pkgs[basePkg].MustStruct("Model")
+	if len(model.Fields) != 3 || model.Fields[2].Type != prevTime.MustGetType("Time") {
+		t.Errorf("reloaded type is expected to reference kept dependency: %v", model.Fields)
+	}
+	service := pkgs[appPkg].MustStruct("Service")
+	if service.Fields[0].Type.Elem() != model {
+		t.Error("dependent package type is expected to reference reloaded type")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	const (
+		watchDir = "testdata/watch"
+		watchPkg = "github.com/kucjac/gentools/parser/testdata/watch"
+	)
+	if err := os.MkdirAll(watchDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(watchDir) })
+	writeFile := func(content string) {
+		if err := os.WriteFile(watchDir+"/watch.go", []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("package watch\n\ntype Foo struct {\n\tID int\n}\n\ntype Bar interface {\n\tBaz()\n}\n")
+
+	pkgs := types.PackageMap{}
+	w, err := Watch(context.Background(), pkgs, LoadConfig{Paths: []string{watchDir}}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("starting watcher failed: %v", err)
+	}
+	defer w.Close()
+
+	writeFile("package watch\n\ntype Foo struct {\n\tID   int\n\tName string\n}\n\ntype Bar interface {\n\tQux()\n}\n")
+	expected := []string{
+		"method Baz removed from interface Bar",
+		"method Qux added to interface Bar",
+		"struct Foo gained field Name",
+	}
+	var events []string
+	timeout := time.After(30 * time.Second)
+	for len(events) < len(expected) {
+		select {
+		case event := <-w.Events:
+			if event.PkgPath != watchPkg {
+				t.Errorf("unexpected event package: %s", event.PkgPath)
+			}
+			events = append(events, event.String())
+		case err := <-w.Errors:
+			t.Fatalf("watcher failed: %v", err)
+		case <-timeout:
+			t.Fatalf("expected events: %v but got: %v", expected, events)
+		}
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected events: %v but got: %v", expected, events)
+	}
+
+	w.Lock()
+	foo := pkgs[watchPkg].MustStruct("Foo")
+	w.Unlock()
+	if len(foo.Fields) != 2 {
+		t.Errorf("watched package is expected to be reloaded: %v", foo.Fields)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Events; ok {
+		t.Error("events channel is expected to be closed")
+	}
+}
+
+func TestWatchRetry(t *testing.T) {
+	const (
+		watchDir = "testdata/watchretry"
+		watchPkg = "github.com/kucjac/gentools/parser/testdata/watchretry"
+	)
+	if err := os.MkdirAll(watchDir+"/dep", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(watchDir) })
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(watchDir+"/"+name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const validDep = "package dep\n\ntype ID int\n"
+	writeFile("dep/dep.go", validDep)
+	writeFile("watch.go", "package watchretry\n\nimport \"github.com/kucjac/gentools/parser/testdata/watchretry/dep\"\n\ntype Foo struct {\n\tID dep.ID\n}\n")
+
+	pkgs := types.PackageMap{}
+	w, err := Watch(context.Background(), pkgs, LoadConfig{Paths: []string{watchDir}}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("starting watcher failed: %v", err)
+	}
+	defer w.Close()
+
+	// The not watched dependency is broken, so that the reload of the watched directory fails.
+	writeFile("dep/dep.go", "package dep\n\ntype ID Undefined\n")
+	writeFile("watch.go", "package watchretry\n\nimport \"github.com/kucjac/gentools/parser/testdata/watchretry/dep\"\n\ntype Foo struct {\n\tID   dep.ID\n\tName string\n}\n")
+	timeout := time.After(30 * time.Second)
+	select {
+	case event := <-w.Events:
+		t.Fatalf("reload is expected to fail but got event: %s", event)
+	case <-w.Errors:
+	case <-timeout:
+		t.Fatal("reload is expected to fail")
+	}
+
+	// Once the dependency is fixed, the failed reload is retried without further changes of the watched files.
+	writeFile("dep/dep.go", validDep)
+	for {
+		select {
+		case event := <-w.Events:
+			if event.String() != "struct Foo gained field Name" {
+				t.Fatalf("unexpected event: %s", event)
+			}
+			return
+		case <-w.Errors:
+		case <-timeout:
+			t.Fatal("failed reload is expected to be retried")
+		}
+	}
+}
+
+func TestLoadPackagesLogger(t *testing.T) {
+	const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+	cfg := LoadConfig{PkgNames: []string{testCasesPkg}, Verbose: true, WithComments: true}
+
+	t.Run("Silent", func(t *testing.T) {
+		output := captureOutput(t, func() {
+			if _, _, err := LoadPackages(cfg); err != nil {
+				t.Errorf("loading packages failed: %v", err)
+			}
+		})
+		if output != "" {
+			t.Errorf("nothing is expected to be written without the hooks, but got:\n%s", output)
+		}
+	})
+
+	t.Run("Hooks", func(t *testing.T) {
+		var (
+			mu       sync.Mutex
+			messages []string
+			events   []Progress
+		)
+		hooked := cfg
+		hooked.Logger = LoggerFunc(func(level LogLevel, msg string, keyvals ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(keyvals)%2 != 0 {
+				t.Errorf("log entry '%s' has odd number of key values: %v", msg, keyvals)
+			}
+			messages = append(messages, level.String()+": "+msg)
+		})
+		hooked.Progress = func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, p)
+		}
+		pkgs, _, err := LoadPackages(hooked)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+
+		for _, expected := range []string{"info: AST packages loaded", "info: gentools packages parsed"} {
+			var found bool
+			for _, msg := range messages {
+				if msg == expected {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("expected log entry '%s' not found in: %v", expected, messages)
+			}
+		}
+
+		phases := map[Phase]int{}
+		started, finished := map[string]bool{}, map[string]bool{}
+		for _, event := range events {
+			switch event.Kind {
+			case PhaseStarted:
+				phases[event.Phase]++
+			case PhaseFinished:
+				phases[event.Phase]--
+			case PackageStarted:
+				started[event.PkgPath] = true
+			case PackageFinished:
+				if !started[event.PkgPath] {
+					t.Errorf("package %s finished before it was started", event.PkgPath)
+				}
+				finished[event.PkgPath] = true
+			}
+		}
+		if len(phases) != 2 || phases[PhaseLoad] != 0 || phases[PhaseParse] != 0 {
+			t.Errorf("expected started and finished load and parse phases, but got: %v", phases)
+		}
+		if len(finished) != len(pkgs) {
+			t.Errorf("expected %d finished packages but got: %d", len(pkgs), len(finished))
+		}
+		if !finished[testCasesPkg] {
+			t.Errorf("package %s is expected to be finished", testCasesPkg)
+		}
+	})
+}
+
+// captureOutput gets everything written to the standard output and error streams, as well as the standard logger,
+// while given function is running.
+func captureOutput(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	log.SetOutput(w)
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	fn()
+
+	os.Stdout, os.Stderr = stdout, stderr
+	log.SetOutput(os.Stderr)
+	w.Close()
+	output := <-done
+	r.Close()
+	return string(output)
+}
+
+func TestLoadPackagesConcurrency(t *testing.T) {
+	const (
+		testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+		importedPkg  = "github.com/kucjac/gentools/parser/testcases/imported"
+	)
+	var (
+		stats    LoadStats
+		finished []string
+	)
+	cfg := LoadConfig{
+		PkgNames:    []string{testCasesPkg},
+		Concurrency: 1,
+		Stats:       &stats,
+		Progress: func(p Progress) {
+			if p.Kind == PackageFinished {
+				finished = append(finished, p.PkgPath)
+			}
+		},
+	}
+	sequential, _, err := LoadPackages(cfg)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if stats.PeakWorkers != 1 {
+		t.Errorf("expected single worker but got: %d", stats.PeakWorkers)
+	}
+	if stats.Packages != len(sequential) {
+		t.Errorf("expected %d packages but got: %d", len(sequential), stats.Packages)
+	}
+	if stats.Types == 0 || stats.Declarations == 0 {
+		t.Errorf("expected types and declarations to be counted: %+v", stats)
+	}
+	if stats.Phases[PhaseLoad] <= 0 || stats.Phases[PhaseParse] <= 0 {
+		t.Errorf("expected load and parse phase durations: %v", stats.Phases)
+	}
+	order := map[string]int{}
+	for i, path := range finished {
+		order[path] = i
+	}
+	if order[importedPkg] > order[testCasesPkg] {
+		t.Errorf("package %s expected to be finished before its dependent %s", importedPkg, testCasesPkg)
+	}
+
+	cfg.Concurrency, cfg.Progress = 8, nil
+	concurrent, _, err := LoadPackages(cfg)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if stats.PeakWorkers < 1 || stats.PeakWorkers > 8 {
+		t.Errorf("expected at most 8 workers but got: %d", stats.PeakWorkers)
+	}
+	if len(concurrent) != len(sequential) {
+		t.Fatalf("expected %d packages but got: %d", len(sequential), len(concurrent))
+	}
+	for path, pkg := range sequential {
+		var expected, actual bytes.Buffer
+		if err = types.EncodePackage(&expected, pkg); err != nil {
+			t.Fatalf("encoding package %s failed: %v", path, err)
+		}
+		if err = types.EncodePackage(&actual, concurrent[path]); err != nil {
+			t.Fatalf("encoding package %s failed: %v", path, err)
+		}
+		if expected.String() != actual.String() {
+			t.Errorf("package %s parsed concurrently doesn't match the sequentially parsed one", path)
+		}
+	}
+}
+
+func TestLoadPackagesPatterns(t *testing.T) {
+	const (
+		testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+		importedPkg  = "github.com/kucjac/gentools/parser/testcases/imported"
+	)
+	t.Run("Paths", func(t *testing.T) {
+		var stats LoadStats
+		pkgs, _, err := LoadPackages(LoadConfig{Paths: []string{"./testcases/..."}, Stats: &stats})
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		if len(stats.Matched) != 2 || stats.Matched[0] != testCasesPkg || stats.Matched[1] != importedPkg {
+			t.Errorf("expected matched packages: %s, %s but got: %v", testCasesPkg, importedPkg, stats.Matched)
+		}
+		for _, path := range stats.Matched {
+			if _, ok := pkgs[path]; !ok {
+				t.Errorf("matched package %s not loaded", path)
+			}
+		}
+	})
+
+	t.Run("PkgNames", func(t *testing.T) {
+		matched, err := MatchPackages(LoadConfig{PkgNames: []string{testCasesPkg + "/...", importedPkg}})
+		if err != nil {
+			t.Fatalf("matching packages failed: %v", err)
+		}
+		if len(matched) != 2 || matched[0] != testCasesPkg || matched[1] != importedPkg {
+			t.Errorf("expected matched packages: %s, %s but got: %v", testCasesPkg, importedPkg, matched)
+		}
+	})
+
+	t.Run("Std", func(t *testing.T) {
+		matched, err := MatchPackages(LoadConfig{PkgNames: []string{"std"}})
+		if err != nil {
+			t.Fatalf("matching packages failed: %v", err)
+		}
+		i := sort.SearchStrings(matched, "net/http")
+		if i == len(matched) || matched[i] != "net/http" {
+			t.Errorf("expected 'std' pattern to match 'net/http' package")
+		}
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		if _, _, err := LoadPackages(LoadConfig{Paths: []string{"./testcases/nonexistent/..."}}); err == nil {
+			t.Error("expected error for the pattern that matches no packages")
+		}
+	})
+}
+
+func TestParsePackageImport(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	t.Setenv("GO111MODULE", "")
+	testCases := map[string]string{
+		"testdata/workspace/lib/sub":                    "example.com/lib/sub",
+		"testdata/workspace/lib/nested/inner":           "example.com/nested/inner",
+		"testdata/workspace/shared/util":                "example.com/shared/util",
+		"testdata/workspace/app":                        "example.com/app",
+		"testdata/workspace/app/vendor/example.com/dep": "example.com/dep",
+		"testcases/imported":                            "github.com/kucjac/gentools/parser/testcases/imported",
+	}
+	for dir, expected := range testCases {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		importPath, err := parsePackageImport(abs, os.Getenv)
+		if err != nil {
+			t.Errorf("resolving import path of %s failed: %v", dir, err)
+			continue
+		}
+		if importPath != expected {
+			t.Errorf("expected import path of %s to be %s but got: %s", dir, expected, importPath)
+		}
+	}
+
+	t.Run("Module", func(t *testing.T) {
+		const testCasesPkg = "github.com/kucjac/gentools/parser/testcases"
+		pkgs, _, err := LoadPackages(LoadConfig{PkgNames: []string{testCasesPkg}})
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		pkg := pkgs[testCasesPkg]
+		if pkg.Module == nil || pkg.Module.Path != "github.com/kucjac/gentools" || !pkg.Module.Main {
+			t.Errorf("expected package to be in the main module github.com/kucjac/gentools but is: %+v", pkg.Module)
+		}
+		dir, err := filepath.Abs("testcases")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkg.Dir != dir {
+			t.Errorf("expected package directory %s but got: %s", dir, pkg.Dir)
+		}
+		if fmtPkg := pkgs["fmt"]; fmtPkg == nil || fmtPkg.Module != nil {
+			t.Errorf("standard library package is not expected to have a module")
+		}
+	})
+}
+
+func TestLoadPackagesEnvironment(t *testing.T) {
+	const subPkg = "example.com/lib/sub"
+	var (
+		driverDir string
+		driverEnv []string
+		patterns  []string
+		stats     LoadStats
+	)
+	cfg := LoadConfig{
+		Paths: []string{"./..."},
+		Dir:   "testdata/workspace/lib",
+		// The workspace is disabled, so that the dependencies of the other workspace modules are not required.
+		Env:   append(os.Environ(), "GOWORK=off"),
+		Stats: &stats,
+		Driver: func(cfg *packages.Config, p ...string) ([]*packages.Package, error) {
+			driverDir, driverEnv = cfg.Dir, cfg.Env
+			patterns = append(patterns, p...)
+			return packages.Load(cfg, p...)
+		},
+	}
+	pkgs, _, err := LoadPackages(cfg)
+	if err != nil {
+		t.Fatalf("loading packages failed: %v", err)
+	}
+	if len(stats.Matched) != 1 || stats.Matched[0] != subPkg {
+		t.Errorf("expected only %s package to be matched but got: %v", subPkg, stats.Matched)
+	}
+	pkg, ok := pkgs[subPkg]
+	if !ok {
+		t.Fatalf("package %s not loaded", subPkg)
+	}
+	if pkg.Module == nil || pkg.Module.Path != "example.com/lib" {
+		t.Errorf("expected package module example.com/lib but got: %+v", pkg.Module)
+	}
+	if _, ok = pkg.GetType("Sub"); !ok {
+		t.Errorf("type Sub not found in package %s", subPkg)
+	}
+
+	if driverDir != cfg.Dir {
+		t.Errorf("expected driver to be run within %s directory but got: %s", cfg.Dir, driverDir)
+	}
+	if len(driverEnv) == 0 || driverEnv[len(driverEnv)-1] != "GOWORK=off" {
+		t.Errorf("expected driver to be run within the config environment")
+	}
+	if len(patterns) != 2 || patterns[1] != subPkg {
+		t.Errorf("expected driver to match the pattern and load %s but got: %v", subPkg, patterns)
+	}
+
+	t.Run("Paths", func(t *testing.T) {
+		paths := cfg
+		// The module root directory has no go files, thus it is not matched.
+		paths.Paths, paths.Driver, paths.Stats = []string{".", "sub"}, nil, &LoadStats{}
+		pkgs, _, err := LoadPackages(paths)
+		if err != nil {
+			t.Fatalf("loading packages failed: %v", err)
+		}
+		if _, ok := pkgs[subPkg]; !ok {
+			t.Errorf("package %s not loaded", subPkg)
+		}
+		if len(paths.Stats.Matched) != 1 || paths.Stats.Matched[0] != subPkg {
+			t.Errorf("expected only %s package to be matched but got: %v", subPkg, paths.Stats.Matched)
+		}
+
+		paths.Paths = []string{"."}
+		if _, _, err = LoadPackages(paths); err == nil {
+			t.Error("expected error for the directory with no go files")
+		}
+		paths.Paths = []string{"missing"}
+		if _, _, err = LoadPackages(paths); err == nil {
+			t.Error("expected error for the nonexistent directory")
+		}
+	})
+}
+
+func TestLoadSource(t *testing.T) {
+	t.Run("Source", func(t *testing.T) {
+		pkgs, diagnostics, err := LoadSource(map[string]string{
+			"model.go": "package source\n\n// Model is the source model.\ntype Model struct {\n\tID int\n}\n",
+			"api/api.go": `package api
+
+import (
+	"fmt"
+
+	"example.com/source"
+)
+
+// Handler handles the models.
+type Handler interface {
+	fmt.Stringer
+	Handle(m *source.Model) error
+}
+`,
+		})
+		if err != nil {
+			t.Fatalf("loading source failed: %v\n%s", err, diagnostics)
+		}
+		model, ok := pkgs[SourceModulePath].GetStruct("Model")
+		if !ok {
+			t.Fatal("type Model not found in the source package")
+		}
+		if model.Comment != "Model is the source model.\n" {
+			t.Errorf("unexpected Model comment: %q", model.Comment)
+		}
+		// The source is read from the overlay, as the temporary files are removed.
+		src, err := model.Pos.Source()
+		if err != nil {
+			t.Fatalf("reading Model source failed: %v", err)
+		}
+		if !strings.Contains(string(src), "Model struct {\n\tID int\n}") {
+			t.Errorf("unexpected Model source: %q", src)
+		}
+		handler, ok := pkgs[SourceModulePath+"/api"].GetInterfaceType("Handler")
+		if !ok {
+			t.Fatal("type Handler not found in the api package")
+		}
+		if len(handler.Methods) != 2 || len(handler.ExplicitMethods) != 1 || handler.ExplicitMethods[0].FuncName != "Handle" {
+			t.Errorf("expected Handler to have String and Handle methods but got: %v", handler.Methods)
+		}
+		if _, err = os.Stat(pkgs[SourceModulePath].Dir); !os.IsNotExist(err) {
+			t.Errorf("expected temporary source directory to be removed")
+		}
+	})
+
+	t.Run("Dir", func(t *testing.T) {
+		dir := t.TempDir()
+		pkgs, _, err := LoadSourceContext(context.Background(), LoadConfig{Dir: dir}, map[string]string{
+			"model.go": "package source\n\n// Model is the source model.\ntype Model struct {\n\tID int\n}\n",
+		})
+		if err != nil {
+			t.Fatalf("loading source failed: %v", err)
+		}
+		model, ok := pkgs[SourceModulePath].GetStruct("Model")
+		if !ok {
+			t.Fatal("type Model not found in the source package")
+		}
+		// The files written into the config Dir are kept, so that the positions remain valid.
+		src, err := model.Pos.Source()
+		if err != nil {
+			t.Fatalf("reading Model source failed: %v", err)
+		}
+		if !strings.Contains(string(src), "Model struct {\n\tID int\n}") {
+			t.Errorf("unexpected Model source: %q", src)
+		}
+	})
+
+	t.Run("Txtar", func(t *testing.T) {
+		pkgs, _, err := LoadTxtar([]byte(`The archive comment.
+-- go.mod --
+module example.com/txtar
+
+go 1.19
+-- txtar.go --
+package txtar
+
+// Version is the txtar package version.
+const Version string = "v1"
+`))
+		if err != nil {
+			t.Fatalf("loading txtar failed: %v", err)
+		}
+		pkg, ok := pkgs["example.com/txtar"]
+		if !ok {
+			t.Fatal("package example.com/txtar not found")
+		}
+		if decl, ok := pkg.Declarations["Version"]; !ok || !decl.Constant {
+			t.Error("constant Version not found in the txtar package")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, _, err := LoadSource(map[string]string{"../outside.go": "package outside\n"}); err == nil {
+			t.Error("expected error for the file outside of the module")
+		}
+	})
+}
+
+func TestParseLineComments(t *testing.T) {
+	pkgs, _, err := LoadTxtar([]byte(`
+-- comments.go --
+package comments
+
+import "fmt"
+
+// Point is the point.
+type Point struct {
+	// X and Y are the coordinates.
+	X, Y int // In pixels.
+	fmt.Stringer // The embedded stringer.
+	// Label is the point label.
+	Label string
+}
+
+// Shape is the shape.
+type Shape interface {
+	// Area gets the area.
+	Area() float64 // In square pixels.
+	Name() string  // The shape name.
+}
+
+// Origin is the origin point.
+var Origin Point
+
+const (
+	// Min and Max are the bounds.
+	Min, Max int = 0, 10 // Inclusive.
+	Default int = 5 // The default value.
+)
+`))
+	if err != nil {
+		t.Fatalf("loading source failed: %v", err)
+	}
+	pkg := pkgs[SourceModulePath]
+
+	point := pkg.MustStruct("Point")
+	expectedFields := []struct{ name, doc, line string }{
+		{"X", "X and Y are the coordinates.\n", "In pixels.\n"},
+		{"Y", "X and Y are the coordinates.\n", "In pixels.\n"},
+		{"Stringer", "", "The embedded stringer.\n"},
+		{"Label", "Label is the point label.\n", ""},
+	}
+	if len(point.Fields) != len(expectedFields) {
+		t.Fatalf("expected %d fields but got: %d", len(expectedFields), len(point.Fields))
+	}
+	for i, expected := range expectedFields {
+		field := point.Fields[i]
+		if field.Name != expected.name || field.Comment != expected.doc || field.LineComment != expected.line {
+			t.Errorf("expected field %s with comments %q, %q but got: %s %q, %q", expected.name, expected.doc, expected.line, field.Name, field.Comment, field.LineComment)
+		}
+	}
+
+	shape, ok := pkg.GetInterfaceType("Shape")
+	if !ok {
+		t.Fatal("type Shape not found")
+	}
+	for _, expected := range []struct{ name, doc, line string }{
+		{"Area", "Area gets the area.\n", "In square pixels.\n"},
+		{"Name", "", "The shape name.\n"},
+	} {
+		method, ok := shape.Method(expected.name)
+		if !ok {
+			t.Fatalf("method %s not found", expected.name)
+		}
+		if method.Comment != expected.doc || method.LineComment != expected.line {
+			t.Errorf("expected method %s comments %q, %q but got: %q, %q", expected.name, expected.doc, expected.line, method.Comment, method.LineComment)
+		}
+	}
+
+	for _, expected := range []struct{ name, doc, line string }{
+		{"Origin", "Origin is the origin point.\n", ""},
+		{"Min", "Min and Max are the bounds.\n", "Inclusive.\n"},
+		{"Max", "Min and Max are the bounds.\n", "Inclusive.\n"},
+		{"Default", "", "The default value.\n"},
+	} {
+		decl, ok := pkg.Declarations[expected.name]
+		if !ok {
+			t.Fatalf("declaration %s not found", expected.name)
+		}
+		if decl.Comment != expected.doc || decl.LineComment != expected.line {
+			t.Errorf("expected declaration %s comments %q, %q but got: %q, %q", expected.name, expected.doc, expected.line, decl.Comment, decl.LineComment)
+		}
+	}
+}
+
+func TestParseMarkers(t *testing.T) {
+	schema := &types.MarkerSchema{}
+	err := schema.Register(
+		types.MarkerDefinition{Name: "gentools:table", Value: types.MarkerString},
+		types.MarkerDefinition{Name: "gentools:index", Value: types.MarkerString, Args: map[string]types.MarkerKind{
+			"unique":  types.MarkerBool,
+			"columns": types.MarkerList,
+		}},
+		types.MarkerDefinition{Name: "gentools:cache", Value: types.MarkerBool},
+		types.MarkerDefinition{Name: "gentools:column", Value: types.MarkerString},
+		types.MarkerDefinition{Name: "gentools:pk", Value: types.MarkerBool},
+		types.MarkerDefinition{Name: "gentools:size", Value: types.MarkerInt},
+		types.MarkerDefinition{Name: "gentools:skip", Value: types.MarkerBool},
+		types.MarkerDefinition{Name: "gentools:enum", Value: types.MarkerList},
+		types.MarkerDefinition{Name: "gentools:readonly"},
+	)
+	if err != nil {
+		t.Fatalf("registering markers failed: %v", err)
+	}
+	if err = schema.Register(types.MarkerDefinition{Name: "gentools:skip"}); err == nil {
+		t.Error("expected duplicated marker registration error")
+	}
+
+	pkgs, diagnostics, err := LoadSourceContext(context.Background(), LoadConfig{Markers: schema}, map[string]string{
+		"models.go": `package models
+
+// User is the user model.
+// +gentools:table=users
+// +gentools:index=idx_name unique,columns={first,last}
+// +k8s:deepcopy-gen=true
+//gentools:cache
+type User struct {
+	// +gentools:column=user_id
+	ID int // +gentools:pk
+	First, Last string // +gentools:size=64
+	Nick, Alias string // +gentools:size=big
+	// +gentools:column="bad
+	Bad string
+}
+
+// Getter gets the value.
+// +gentools:unknown
+type Getter interface {
+	// Get gets the value.
+	//gentools:skip
+	Get() int
+}
+
+// +gentools:table=1
+// +gentools:readonly
+// +gentools:enum
+// +gentools:column
+type Names []string
+
+// New creates the user.
+//go:noinline
+//gentools:skip
+func New() *User { return &User{} }
+
+// +gentools:enum={1, 2, "three"}
+var Default int = 1
+`,
+	})
+	if err != nil {
+		t.Fatalf("loading source failed: %v", err)
+	}
+	pkg := pkgs[SourceModulePath]
+
+	user := pkg.MustStruct("User")
+	if len(user.Markers) != 4 {
+		t.Fatalf("expected 4 User markers but got: %v", user.Markers)
+	}
+	if table, ok := user.Markers.Get("gentools:table"); !ok || table.Value != "users" || table.Pos.Line != 4 {
+		t.Errorf("expected gentools:table=users marker at line 4 but got: %v %v", table, table.Pos)
+	}
+	index, ok := user.Markers.Get("gentools:index")
+	if !ok {
+		t.Fatal("gentools:index marker not found")
+	}
+	if name, _ := index.StringValue(); name != "idx_name" {
+		t.Errorf("expected index name idx_name but got: %v", index.Value)
+	}
+	if unique, _ := index.Arg("unique"); unique != true {
+		t.Errorf("expected unique index but got: %v", unique)
+	}
+	if columns, _ := index.Arg("columns"); len(columns.([]interface{})) != 2 {
+		t.Errorf("expected two index columns but got: %v", columns)
+	}
+	if deepCopy, ok := user.Markers.Get("k8s:deepcopy-gen"); !ok || deepCopy.Value != true {
+		t.Errorf("expected unvalidated k8s:deepcopy-gen marker but got: %v", deepCopy)
+	}
+	if !user.Markers.Has("gentools:cache") {
+		t.Error("expected directive like gentools:cache marker")
+	}
+
+	for i, expected := range []struct {
+		field string
+		names []string
+	}{
+		{"ID", []string{"gentools:column", "gentools:pk"}},
+		{"First", []string{"gentools:size"}},
+		{"Last", []string{"gentools:size"}},
+		{"Nick", nil},
+		{"Alias", nil},
+		{"Bad", nil},
+	} {
+		field := user.Fields[i]
+		if field.Name != expected.field {
+			t.Fatalf("expected field %s but got: %s", expected.field, field.Name)
+		}
+		var names []string
+		for _, m := range field.Markers {
+			names = append(names, m.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(expected.names, ",") {
+			t.Errorf("expected field %s markers %v but got: %v", expected.field, expected.names, names)
+		}
+	}
+	if size, _ := user.Fields[1].Markers[0].IntValue(); size != 64 {
+		t.Errorf("expected size 64 but got: %v", user.Fields[1].Markers[0].Value)
+	}
+
+	getter, ok := pkg.GetInterfaceType("Getter")
+	if !ok {
+		t.Fatal("type Getter not found")
+	}
+	if len(getter.Markers) != 0 {
+		t.Errorf("expected unknown marker to be skipped but got: %v", getter.Markers)
+	}
+	if get, ok := getter.Method("Get"); !ok || !get.Markers.Has("gentools:skip") {
+		t.Error("expected Get method gentools:skip marker")
+	}
+
+	names, ok := pkg.GetType("Names")
+	if !ok {
+		t.Fatal("type Names not found")
+	}
+	// The int like value is converted to the string kind of the marker definition.
+	if table, _ := names.(*types.Alias).Markers.Get("gentools:table"); table.Value != "1" {
+		t.Errorf("expected Names table '1' but got: %#v", table.Value)
+	}
+	// The definition without the value kind is the bool marker, whereas the list and string markers require the value.
+	if markers := names.(*types.Alias).Markers; !markers.Has("gentools:readonly") || markers.Has("gentools:enum") || markers.Has("gentools:column") {
+		t.Errorf("expected Names readonly marker without the enum and column markers but got: %v", markers)
+	}
+	if fn, ok := pkg.GetFunction("New"); !ok || len(fn.Markers) != 1 || !fn.Markers.Has("gentools:skip") {
+		t.Errorf("expected New function gentools:skip marker but got: %v", fn)
+	}
+	enum, _ := pkg.Declarations["Default"].Markers.Get("gentools:enum")
+	if values, _ := enum.ListValue(); len(values) != 3 || values[0] != 1 || values[2] != "three" {
+		t.Errorf("expected Default enum values but got: %#v", enum.Value)
+	}
+
+	// The invalid marker of the field with multiple names is reported once.
+	var messages []string
+	for _, d := range diagnostics {
+		if d.Severity != SeverityWarning || !d.Pos.IsValid() {
+			t.Errorf("expected positioned warning but got: %v", d)
+		}
+		messages = append(messages, d.Message)
+	}
+	expectedMessages := []string{"gentools:size=big", "unterminated quote", "unknown marker", "gentools:enum", "gentools:column"}
+	if len(messages) != len(expectedMessages) {
+		t.Fatalf("expected %d marker warnings but got: %v", len(expectedMessages), diagnostics)
+	}
+	for i, expected := range expectedMessages {
+		if !strings.Contains(messages[i], expected) {
+			t.Errorf("expected marker warning containing %q but got: %s", expected, messages[i])
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err = types.EncodePackage(&encoded, pkg); err != nil {
+		t.Fatalf("encoding package failed: %v", err)
+	}
+	decoded, err := types.DecodePackage(bytes.NewReader(encoded.Bytes()), types.PackageMap{})
+	if err != nil {
+		t.Fatalf("decoding package failed: %v", err)
+	}
+	decodedEnum, _ := decoded.Declarations["Default"].Markers.Get("gentools:enum")
+	if values, _ := decodedEnum.ListValue(); len(values) != 3 || values[0] != 1 || !decodedEnum.HasValue {
+		t.Errorf("expected decoded enum values but got: %#v", decodedEnum.Value)
+	}
+}
+
+func TestParsePackageFiles(t *testing.T) {
+	pkgs, _, err := LoadSource(map[string]string{
+		"doc.go": `// Package source is the package with the files.
+//
+// It has a longer description.
+package source
+`,
+		"gen.go": `// Code generated by gentools; DO NOT EDIT.
+
+//go:build !windows
+
+package source
+
+//go:generate echo "hello world" $GOFILE
+//go:generate -command foo go run foo.go
+
+// Kind is the generated kind.
+type Kind int
+`,
+		"embed.go": `// +build !plan9
+// +build !js
+
+package source
+
+import (
+	"embed"
+	_ "unsafe"
+)
+
+//go:embed static/*.txt
+var static embed.FS
+
+//go:linkname helper example.com/source.helper
+func helper() int { return 1 }
+`,
+		"static/a.txt": "a",
+	})
+	if err != nil {
+		t.Fatalf("loading source failed: %v", err)
+	}
+	pkg := pkgs[SourceModulePath]
+	if pkg.Comment != "Package source is the package with the files.\n\nIt has a longer description.\n" {
+		t.Errorf("unexpected package comment: %q", pkg.Comment)
+	}
+	if len(pkg.Files) != 3 {
+		t.Fatalf("expected 3 files but got: %d", len(pkg.Files))
+	}
+
+	expected := []struct {
+		name       string
+		constraint string
+		generated  bool
+		directives []string
+	}{
+		{"doc.go", "", false, nil},
+		{"embed.go", "!plan9 && !js", false, []string{"go:embed", "go:linkname"}},
+		{"gen.go", "!windows", true, []string{"go:build", "go:generate", "go:generate"}},
+	}
+	for i, f := range pkg.Files {
+		e := expected[i]
+		if filepath.Base(f.Path) != e.name || !filepath.IsAbs(f.Path) {
+			t.Errorf("expected file %s but got: %s", e.name, f.Path)
+			continue
+		}
+		if f.BuildConstraint != e.constraint || f.Generated != e.generated {
+			t.Errorf("expected file %s constraint %q and generated %v but got: %q, %v", e.name, e.constraint, e.generated, f.BuildConstraint, f.Generated)
+		}
+		var names []string
+		for _, d := range f.Directives {
+			if !d.Pos.IsValid() {
+				t.Errorf("expected directive %s position", d.Name)
+			}
+			names = append(names, d.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(e.directives, ",") {
+			t.Errorf("expected file %s directives %v but got: %v", e.name, e.directives, names)
+		}
+	}
+
+	generate := pkg.Files[2].FindDirectives("go:generate")
+	if len(generate) != 2 {
+		t.Fatalf("expected 2 go:generate directives but got: %v", generate)
+	}
+	if args := generate[0].Args; len(args) != 3 || args[1] != "hello world" || args[2] != "$GOFILE" {
+		t.Errorf("unexpected go:generate arguments: %q", args)
+	}
+	if generate[0].Text != `echo "hello world" $GOFILE` || generate[0].Pos.Line != 7 {
+		t.Errorf("unexpected go:generate directive: %q at %v", generate[0].Text, generate[0].Pos)
+	}
+	if linkname := pkg.Files[1].FindDirectives("go:linkname"); len(linkname) != 1 || linkname[0].Args[1] != "example.com/source.helper" {
+		t.Errorf("unexpected go:linkname directive: %v", linkname)
+	}
+}
+
+func TestParseTypeAliases(t *testing.T) {
+	pkgs, _, err := LoadTxtar([]byte(`
+-- aliases.go --
+package aliases
+
+import "io"
+
+// ID is the defined identifier type.
+type ID int64
+
+func (i ID) String() string { return "" }
+
+// AliasID is the alias of the identifier.
+type AliasID = ID
+
+type Reader = io.Reader
+
+type Ints = []int
+
+type Stringer interface {
+	String() string
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type IntPair = Pair[int, string]
+
+type Source interface {
+	Source() io.Reader
+}
+
+type source struct{}
+
+func (source) Source() Reader { return nil }
+`))
+	if err != nil {
+		t.Fatalf("loading source failed: %v", err)
+	}
+	pkg := pkgs[SourceModulePath]
+	getAlias := func(name string) *types.Alias {
+		tp, ok := pkg.GetType(name)
+		if !ok {
+			t.Fatalf("type %s not found", name)
+		}
+		alias, ok := tp.(*types.Alias)
+		if !ok {
+			t.Fatalf("expected type %s to be an alias but is: %T", name, tp)
+		}
+		return alias
+	}
+
+	id, aliasID := getAlias("ID"), getAlias("AliasID")
+	if id.TypeAlias || !aliasID.TypeAlias {
+		t.Errorf("expected only AliasID to be the type alias but got: %v, %v", id.TypeAlias, aliasID.TypeAlias)
+	}
+	if aliasID.Type != types.Type(id) || !aliasID.Equal(id) || !id.Equal(aliasID) {
+		t.Error("expected AliasID to be identical to ID")
+	}
+	if id.Equal(types.Int64) || types.Int64.Equal(id) {
+		t.Error("expected defined type ID to differ from int64")
+	}
+	if aliasID.Comment != "AliasID is the alias of the identifier.\n" || id.Comment != "ID is the defined identifier type.\n" {
+		t.Errorf("unexpected alias comments: %q, %q", aliasID.Comment, id.Comment)
+	}
+	stringer, _ := pkg.GetInterfaceType("Stringer")
+	if !types.Implements(aliasID, stringer) || !types.Implements(types.PointerTo(aliasID), stringer) {
+		t.Error("expected AliasID to implement Stringer")
+	}
+
+	reader, ok := pkgs["io"].GetInterfaceType("Reader")
+	if !ok {
+		t.Fatal("io.Reader not found")
+	}
+	if readerAlias := getAlias("Reader"); !readerAlias.TypeAlias || !readerAlias.Equal(reader) || !reader.Equal(readerAlias) {
+		t.Error("expected Reader to be identical to io.Reader")
+	}
+	if reader.Comment == "" || strings.Contains(reader.Comment, "Reader = ") {
+		t.Errorf("expected io.Reader to keep its comment but got: %q", reader.Comment)
+	}
+	if ints := getAlias("Ints"); !ints.TypeAlias || !ints.Equal(types.SliceOf(types.Int)) {
+		t.Errorf("expected Ints to be identical to []int but got: %v", ints.Type)
+	}
+	if intPair := getAlias("IntPair"); !intPair.TypeAlias || intPair.Kind() != types.KindStruct {
+		t.Errorf("expected IntPair to be the alias of the instantiated struct but got: %v", intPair.Type)
+	}
+
+	// The method result of the alias type matches the interface method result of the aliased type.
+	sourceIface, _ := pkg.GetInterfaceType("Source")
+	sourceStruct, _ := pkg.GetStruct("source")
+	if !sourceStruct.Implements(sourceIface, false) {
+		t.Error("expected source to implement Source")
+	}
+
+	var encoded bytes.Buffer
+	if err = types.EncodePackage(&encoded, pkg); err != nil {
+		t.Fatalf("encoding package failed: %v", err)
+	}
+	decoded, err := types.DecodePackage(bytes.NewReader(encoded.Bytes()), pkgs)
+	if err != nil {
+		t.Fatalf("decoding package failed: %v", err)
+	}
+	if tp, ok := decoded.GetType("Reader"); !ok || !tp.(*types.Alias).TypeAlias || !tp.Equal(reader) {
+		t.Error("expected decoded Reader to be the type alias")
+	}
+}
+
+func TestLoadPackagesFilters(t *testing.T) {
+	files := map[string]string{
+		"app.go": `package app
+
+import (
+	"example.com/source/dep"
+	"example.com/source/internal/secret"
+)
+
+type App struct {
+	Client  *dep.Client
+	Secret  secret.Key
+	Holders []secret.Holder[int]
+	hidden  bool
+}
+
+func (a *App) run() {}
+`,
+		"dep/dep.go": `package dep
+
+type Client struct {
+	// Name is the client name.
+	Name    string
+	options options
+	// Timeout is the client timeout.
+	Timeout int
+}
+
+func (c *Client) Do() {}
+
+func (c *Client) reset() {}
+
+type options struct{}
+
+func helper() {}
+
+// Node is the sealed interface implemented only by the package types.
+type Node interface {
+	Pos() int
+	node()
+}
+
+type Ident struct{}
+
+func (Ident) Pos() int { return 0 }
+
+func (Ident) node() {}
+`,
+		"internal/secret/secret.go": `package secret
+
+type Key struct {
+	Value string
+}
+
+type Holder[T any] struct {
+	Value T
+}
+`,
+	}
+	cfg := LoadConfig{
+		Paths:        []string{"."},
+		WithComments: true,
+		ExportedOnly: true,
+		Exclude:      []string{SourceModulePath + "/internal/..."},
+	}
+	pkgs, _, err := LoadSourceContext(context.Background(), cfg, files)
+	if err != nil {
+		t.Fatalf("loading source failed: %v", err)
+	}
+	if _, ok := pkgs[SourceModulePath+"/internal/secret"]; ok {
+		t.Error("expected excluded package not to be mapped")
+	}
+
+	app, ok := pkgs[SourceModulePath]
+	if !ok {
+		t.Fatal("requested package not found")
+	}
+	tp, ok := app.GetType("App")
+	if !ok {
+		t.Fatal("App type not found")
+	}
+	st := tp.(*types.Struct)
+	if len(st.Fields) != 4 || len(st.Methods) != 1 {
+		t.Fatalf("expected requested package to be mapped in full but got %d fields and %d methods", len(st.Fields), len(st.Methods))
+	}
+	key, ok := st.Fields[1].Type.(*types.Opaque)
+	if !ok {
+		t.Fatalf("expected excluded type to be opaque but is: %T", st.Fields[1].Type)
+	}
+	if key.FullName() != SourceModulePath+"/internal/secret/Key" || key.UnderlyingKind != types.KindStruct {
+		t.Errorf("unexpected opaque type: %s %s", key.FullName(), key.UnderlyingKind)
+	}
+	if zero := key.Zero(true, SourceModulePath); zero != "secret.Key{}" {
+		t.Errorf("expected opaque struct zero value to be composite literal but got: %s", zero)
+	}
+	for kind, expected := range map[types.Kind]string{types.KindPtr: "nil", types.KindMap: "nil", types.KindFunc: "nil", types.KindInt: "0", types.KindString: "\"\"", types.KindBool: "false"} {
+		if zero := (&types.Opaque{TypeName: "Key", UnderlyingKind: kind}).Zero(true, ""); zero != expected {
+			t.Errorf("expected opaque %s zero value to be %s but got: %s", kind, expected, zero)
+		}
+	}
+	holders, ok := st.Fields[2].Type.(*types.Array)
+	if !ok {
+		t.Fatalf("expected Holders to be a slice but is: %T", st.Fields[2].Type)
+	}
+	holder, ok := holders.Type.(*types.Opaque)
+	if !ok || len(holder.TypeArgs) != 1 || holder.TypeArgs[0].Kind() != types.KindInt {
+		t.Errorf("expected opaque generic instance with int type argument but got: %v", holders.Type)
+	}
+
+	dep, ok := pkgs[SourceModulePath+"/dep"]
+	if !ok {
+		t.Fatal("dependency package not found")
+	}
+	if _, ok = dep.GetType("options"); ok {
+		t.Error("expected unexported dependency type not to be mapped")
+	}
+	if _, ok = dep.GetFunction("helper"); ok {
+		t.Error("expected unexported dependency function not to be mapped")
+	}
+	tp, ok = dep.GetType("Client")
+	if !ok {
+		t.Fatal("Client type not found")
+	}
+	client := tp.(*types.Struct)
+	if len(client.Fields) != 2 || client.Fields[0].Name != "Name" || client.Fields[1].Name != "Timeout" {
+		t.Fatalf("expected only exported Client fields but got: %v", client.Fields)
+	}
+	if client.Fields[1].Comment != "Timeout is the client timeout.\n" {
+		t.Errorf("unexpected Timeout field comment: %q", client.Fields[1].Comment)
+	}
+	if len(client.Methods) != 2 || client.Methods[0].FuncName != "Do" || client.Methods[1].FuncName != "reset" {
+		t.Errorf("expected all Client methods but got: %v", client.Methods)
+	}
+
+	node, ok := dep.GetInterfaceType("Node")
+	if !ok {
+		t.Fatal("Node interface not found")
+	}
+	ident, ok := dep.GetStruct("Ident")
+	if !ok {
+		t.Fatal("Ident type not found")
+	}
+	if len(node.Methods) != 2 || !ident.Implements(node, false) {
+		t.Errorf("expected Ident to implement the sealed Node interface: %v, %v", node.Methods, ident.Methods)
+	}
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	testCases := []struct {
+		pattern, path string
+		matched       bool
+	}{
+		{"net/http", "net/http", true},
+		{"net/http", "net/http/httptest", false},
+		{"net/...", "net", true},
+		{"net/...", "net/http/httptest", true},
+		{"net/...", "network", false},
+		{"example.com/*/internal", "example.com/foo/internal", true},
+		{"example.com/*/internal", "example.com/foo/bar/internal", false},
+		{"example.com/.../internal/...", "example.com/foo/bar/internal/x", true},
+	}
+	for _, tc := range testCases {
+		if matched := matchPathPattern(tc.pattern, tc.path); matched != tc.matched {
+			t.Errorf("matchPathPattern(%q, %q) = %v, expected %v", tc.pattern, tc.path, matched, tc.matched)
+		}
+	}
+}
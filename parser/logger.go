@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LogLevel is the level of the loader log entry.
+type LogLevel int
+
+// Enumerated log levels.
+const (
+	LogDebug LogLevel = iota + 1
+	LogInfo
+	LogWarning
+)
+
+// String implements fmt.Stringer interface.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Logger is the structured logger of the loader. The attributes of the entry are given as the key-value pairs
+// i.e.: "package", "github.com/my/pkg". The logger might be called concurrently by the packages being parsed.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc is the function adapter of the Logger interface.
+type LoggerFunc func(level LogLevel, msg string, keyvals ...interface{})
+
+// Log implements Logger interface.
+func (f LoggerFunc) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+// NewStdLogger creates the Logger that writes the entries using given standard library logger
+// i.e.: 'info: packages parsed elapsed=1.2s'.
+func NewStdLogger(l *log.Logger) Logger {
+	return LoggerFunc(func(level LogLevel, msg string, keyvals ...interface{}) {
+		sb := strings.Builder{}
+		sb.WriteString(level.String())
+		sb.WriteString(": ")
+		sb.WriteString(msg)
+		for i := 0; i < len(keyvals); i += 2 {
+			sb.WriteRune(' ')
+			if i+1 == len(keyvals) {
+				fmt.Fprint(&sb, keyvals[i])
+				break
+			}
+			fmt.Fprintf(&sb, "%v=%v", keyvals[i], keyvals[i+1])
+		}
+		l.Print(sb.String())
+	})
+}
+
+// ProgressKind is the kind of the load progress event.
+type ProgressKind int
+
+// Enumerated progress kinds.
+const (
+	PhaseStarted ProgressKind = iota + 1
+	PhaseFinished
+	PackageStarted
+	PackageFinished
+)
+
+// String implements fmt.Stringer interface.
+func (k ProgressKind) String() string {
+	switch k {
+	case PhaseStarted:
+		return "phase started"
+	case PhaseFinished:
+		return "phase finished"
+	case PackageStarted:
+		return "package started"
+	case PackageFinished:
+		return "package finished"
+	default:
+		return "unknown"
+	}
+}
+
+// Phase is the phase of the packages load.
+type Phase string
+
+// Enumerated load phases.
+const (
+	// PhaseLoad is the phase of loading the packages syntax and type information.
+	PhaseLoad Phase = "load"
+	// PhaseCache is the phase of restoring the packages from the LoadConfig.CacheDir.
+	PhaseCache Phase = "cache"
+	// PhaseParse is the phase of parsing the loaded packages.
+	PhaseParse Phase = "parse"
+)
+
+// Progress is the load progress event. The events of the packages are reported concurrently, as the packages
+// are parsed in parallel.
+type Progress struct {
+	Kind  ProgressKind
+	Phase Phase
+	// PkgPath is the path of the started or finished package.
+	PkgPath string
+	// Elapsed is the duration of the finished phase or package.
+	Elapsed time.Duration
+}
+
+// log writes the entry with the config logger. The debug entries are written only in the verbose mode.
+func (c *LoadConfig) log(level LogLevel, msg string, keyvals ...interface{}) {
+	if c.Logger == nil || (level == LogDebug && !c.Verbose) {
+		return
+	}
+	c.Logger.Log(level, msg, keyvals...)
+}
+
+// lateDiagnostic passes the diagnostic reported after the load has returned to the LazyDiagnostics callback.
+// If the callback is not defined, the diagnostic is written with the config logger.
+func (c *LoadConfig) lateDiagnostic(d Diagnostic) {
+	if c.LazyDiagnostics != nil {
+		c.LazyDiagnostics(d)
+		return
+	}
+	c.log(LogWarning, "lazy dependency diagnostic", "diagnostic", d.String())
+}
+
+// progress reports the progress event with the config callback.
+func (c *LoadConfig) progress(event Progress) {
+	if c.Progress != nil {
+		c.Progress(event)
+	}
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"go/ast"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// parsesComments checks if the package comments are parsed, which is required both for the comments
+// and for the markers.
+func (c *LoadConfig) parsesComments() bool {
+	return c.WithComments || c.Markers != nil
+}
+
+// markersCacheKey gets the fields of the marker schema, that changes the parsed markers.
+func (c *LoadConfig) markersCacheKey() []string {
+	if c.Markers == nil {
+		return nil
+	}
+	var fields []string
+	for _, def := range c.Markers.Definitions() {
+		fields = append(fields, def.String())
+	}
+	return fields
+}
+
+// parseMarkers gets the markers of the declaration with given name from its comment groups. The markers within
+// the namespaces of the LoadConfig.Markers schema are validated, and the malformed or unknown ones are reported
+// as warnings and skipped. The malformed markers of other namespaces are skipped silently, as these might be
+// the directives of other tools.
+func (r *rootPackage) parseMarkers(name string, groups ...*ast.CommentGroup) types.Markers {
+	schema := r.loadConfig.Markers
+	var markers types.Markers
+	for _, cg := range groups {
+		if cg == nil {
+			continue
+		}
+		// The directive like markers i.e.: '//gentools:skip' are not a part of the comment group text.
+		for _, c := range cg.List {
+			text, ok := types.MarkerText(c.Text)
+			if !ok {
+				continue
+			}
+			covered := schema != nil && schema.Covers(text)
+			m, err := types.ParseMarker(text)
+			if err == nil && covered {
+				m, err = schema.Validate(m)
+			}
+			if err != nil {
+				if covered {
+					r.warnf(name, c.Slash, "invalid marker: '%s': %v", text, err)
+				}
+				continue
+			}
+			m.Pos = r.tokenPosition(c.Slash)
+			markers = append(markers, m)
+		}
+	}
+	return markers
+}
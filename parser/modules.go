@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// moduleRoot is the directory of the module, whose packages import paths are resolved relative to it.
+type moduleRoot struct {
+	path string
+	dir  string
+	// main states if the module is the main module, whose vendor directory contains its dependencies.
+	main bool
+}
+
+// moduleImportPath gets the import path of the package in given directory. The directory is resolved within
+// the go.work workspace modules, the local directories of the replace directives, the vendor directories
+// of the main modules and the nearest module. The second result is false if the directory is not within any module.
+func moduleImportPath(srcDir string, getenv func(string) string) (string, bool, error) {
+	roots, err := moduleRoots(srcDir, getenv)
+	if err != nil {
+		return "", false, err
+	}
+	var (
+		best *moduleRoot
+		rel  string
+	)
+	for i := range roots {
+		r, ok := relativeDir(roots[i].dir, srcDir)
+		// The replacement directories are listed first, thus these are preferred over the modules in the same directory.
+		if ok && (best == nil || len(roots[i].dir) > len(best.dir)) {
+			best, rel = &roots[i], r
+		}
+	}
+	if best == nil {
+		return "", false, nil
+	}
+	if best.main && strings.HasPrefix(rel, "vendor/") {
+		// The vendored packages are imported by their original paths.
+		return strings.TrimPrefix(rel, "vendor/"), true, nil
+	}
+	return path.Join(best.path, rel), true, nil
+}
+
+// moduleRoots gets the module roots that might contain given directory. The replacement directories are listed first.
+func moduleRoots(srcDir string, getenv func(string) string) ([]moduleRoot, error) {
+	var (
+		replaces, roots []moduleRoot
+		mainDirs        []string
+	)
+	nearest, hasModule := findParentDir(srcDir, "go.mod")
+	if workFile := goWorkFile(srcDir, getenv); workFile != "" {
+		data, err := ioutil.ReadFile(workFile)
+		if err != nil {
+			return nil, err
+		}
+		workDir := filepath.Dir(workFile)
+		directives := parseModDirectives(data, "use", "replace")
+		for _, args := range directives["use"] {
+			if len(args) != 0 {
+				mainDirs = append(mainDirs, resolveModDir(workDir, args[0]))
+			}
+		}
+		replaces = append(replaces, localReplaces(workDir, directives["replace"])...)
+	} else if hasModule {
+		mainDirs = append(mainDirs, nearest)
+	}
+
+	for _, dir := range mainDirs {
+		f, err := parseModFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, moduleRoot{path: modulePath(f), dir: dir, main: true})
+		for _, r := range f.Replace {
+			if r.New.Version == "" && modfile.IsDirectoryPath(r.New.Path) {
+				replaces = append(replaces, moduleRoot{path: r.Old.Path, dir: resolveModDir(dir, r.New.Path)})
+			}
+		}
+		if dir == nearest {
+			hasModule = false
+		}
+	}
+	if hasModule {
+		// The nearest module is neither the main module nor the workspace module i.e. a nested module.
+		f, err := parseModFile(nearest)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, moduleRoot{path: modulePath(f), dir: nearest})
+	}
+	return append(replaces, roots...), nil
+}
+
+// parseModFile parses the go.mod file of the module in given directory.
+func parseModFile(dir string) (*modfile.File, error) {
+	fileName := filepath.Join(dir, "go.mod")
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.ParseLax(fileName, data, nil)
+}
+
+// modulePath gets the module path of the parsed go.mod file.
+func modulePath(f *modfile.File) string {
+	if f.Module == nil {
+		return ""
+	}
+	return f.Module.Mod.Path
+}
+
+// goWorkFile gets the go.work file used for given directory. The GOWORK environment variable might define
+// the workspace file, or disable the workspace mode with the 'off' value.
+func goWorkFile(srcDir string, getenv func(string) string) string {
+	switch gowork := getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+		dir, ok := findParentDir(srcDir, "go.work")
+		if !ok {
+			return ""
+		}
+		return filepath.Join(dir, "go.work")
+	default:
+		return gowork
+	}
+}
+
+// findParentDir finds the nearest directory containing given file, starting from the given directory.
+func findParentDir(dir, fileName string) (string, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, fileName)); err == nil && !info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// relativeDir gets the slash separated path of the directory relative to given root. The second result is false
+// if the directory is not within the root.
+func relativeDir(root, dir string) (string, bool) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// localReplaces gets the module roots of the go.work replace directives pointing at the local directories.
+func localReplaces(dir string, replaces [][]string) []moduleRoot {
+	var roots []moduleRoot
+	for _, args := range replaces {
+		// The replace directive is in the form: 'old [version] => new [version]'.
+		for i, arg := range args {
+			if arg != "=>" || i+1 >= len(args) {
+				continue
+			}
+			if target := args[i+1]; modfile.IsDirectoryPath(target) {
+				roots = append(roots, moduleRoot{path: args[0], dir: resolveModDir(dir, target)})
+			}
+			break
+		}
+	}
+	return roots
+}
+
+func resolveModDir(baseDir, dir string) string {
+	dir = filepath.FromSlash(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// parseModDirectives gets the arguments of the directives with given verbs from the go.work file content.
+// Both the single line and the block directives are supported. The go.mod files are parsed by the modfile package,
+// whereas the go.work files are parsed here, as the golang.org/x/mod v0.5.1 doesn't provide the go.work parser.
+func parseModDirectives(data []byte, verbs ...string) map[string][][]string {
+	wanted := make(map[string]bool, len(verbs))
+	for _, verb := range verbs {
+		wanted[verb] = true
+	}
+	result := map[string][][]string{}
+	var block string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, field := range fields {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				fields[i] = unquoted
+			}
+		}
+		switch {
+		case block != "":
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if wanted[block] {
+				result[block] = append(result[block], fields)
+			}
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		case wanted[fields[0]]:
+			result[fields[0]] = append(result[fields[0]], fields[1:])
+		}
+	}
+	return result
+}
+
+// packageModule gets the module of the loaded package.
+func packageModule(pkg *packages.Package) *types.Module {
+	m := pkg.Module
+	if m == nil {
+		return nil
+	}
+	module := &types.Module{Path: m.Path, Version: m.Version, Dir: m.Dir, Main: m.Main}
+	if m.Replace != nil {
+		module.Dir = m.Replace.Dir
+		if m.Replace.Version != "" {
+			module.Version = m.Replace.Version
+		}
+	}
+	return module
+}
+
+// packageDir gets the directory of the loaded package files.
+func packageDir(pkg *packages.Package) string {
+	for _, files := range [][]string{pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles} {
+		if len(files) != 0 {
+			return filepath.Dir(files[0])
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	gotypes "go/types"
 	"sync"
 
 	"github.com/kucjac/gentools/types"
@@ -10,6 +11,37 @@ type packageMap struct {
 	sync.Mutex
 	pkgMap             map[string]*types.Package
 	pkgTypesinProgress map[*types.Package]map[string]types.Type
+	typeParams         map[*gotypes.TypeParam]*types.TypeParam
+	instances          map[*gotypes.TypeName][]namedInstance
+	diagnostics        Diagnostics
+	// build is the build context of currently parsed packages.
+	build *types.BuildContext
+	// merge states if currently parsed packages should be merged with already parsed ones.
+	merge bool
+	// fileSets are the keys of the package file sets already parsed under the loaded build contexts.
+	fileSets map[string]struct{}
+	// stats are the statistics of the current load.
+	stats LoadStats
+	// matched are the sorted import paths of the packages matched by the config Paths and PkgNames.
+	matched []string
+	// parsed are the paths of the packages parsed or restored from the cache by the current load.
+	parsed map[string]struct{}
+	// requested are the paths of the packages requested to load, which are mapped regardless of the filters.
+	requested map[string]struct{}
+	// finished states if the diagnostics were already returned by the load.
+	finished bool
+	// errors is the number of the error diagnostics reported by the parser.
+	errors int
+	// lazyTypes is the number of the dependency types mapped lazily.
+	lazyTypes int
+	// overlay is the config overlay with the absolute file paths, referenced by the positions of the parsed types.
+	overlay *types.Overlay
+}
+
+// namedInstance is the instantiated generic named type along with its parsed type.
+type namedInstance struct {
+	named *gotypes.Named
+	tp    types.Type
 }
 
 func (p *packageMap) read(key string) (*types.Package, bool) {
@@ -31,3 +63,68 @@ func (p *packageMap) newPackage(pkgPath, identifier string) *types.Package {
 	p.write(pkgPath, pkg)
 	return pkg
 }
+
+// typeParam gets or creates the type parameter definition for given go/types TypeParam.
+// The second result states if the type param was newly created.
+func (p *packageMap) typeParam(tp *gotypes.TypeParam) (*types.TypeParam, bool) {
+	p.Lock()
+	defer p.Unlock()
+	if p.typeParams == nil {
+		p.typeParams = map[*gotypes.TypeParam]*types.TypeParam{}
+	}
+	if t, ok := p.typeParams[tp]; ok {
+		return t, false
+	}
+	t := &types.TypeParam{ParamName: tp.Obj().Name(), Index: tp.Index()}
+	p.typeParams[tp] = t
+	return t, true
+}
+
+// setTypeParam sets the type parameter definition for given go/types TypeParam.
+func (p *packageMap) setTypeParam(tp *gotypes.TypeParam, t *types.TypeParam) {
+	p.Lock()
+	defer p.Unlock()
+	if p.typeParams == nil {
+		p.typeParams = map[*gotypes.TypeParam]*types.TypeParam{}
+	}
+	p.typeParams[tp] = t
+}
+
+// reserveLazyType reserves the lazily mapped type within given limit. The result is false if the limit is reached.
+func (p *packageMap) reserveLazyType(limit int) bool {
+	p.Lock()
+	defer p.Unlock()
+	if limit > 0 && p.lazyTypes >= limit {
+		return false
+	}
+	p.lazyTypes++
+	return true
+}
+
+// readInstance gets the type of already parsed instance of the generic named type.
+func (p *packageMap) readInstance(named *gotypes.Named) (types.Type, bool) {
+	p.Lock()
+	defer p.Unlock()
+	for _, in := range p.instances[named.Obj()] {
+		if gotypes.Identical(in.named, named) {
+			return in.tp, true
+		}
+	}
+	return nil, false
+}
+
+// writeInstance stores the parsed instance of the generic named type. The instance already parsed by another
+// package is kept.
+func (p *packageMap) writeInstance(named *gotypes.Named, tp types.Type) {
+	p.Lock()
+	defer p.Unlock()
+	if p.instances == nil {
+		p.instances = map[*gotypes.TypeName][]namedInstance{}
+	}
+	for _, in := range p.instances[named.Obj()] {
+		if gotypes.Identical(in.named, named) {
+			return
+		}
+	}
+	p.instances[named.Obj()] = append(p.instances[named.Obj()], namedInstance{named: named, tp: tp})
+}
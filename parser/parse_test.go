@@ -2,13 +2,14 @@ package parser
 
 import (
 	"go/constant"
+	"strings"
 	"testing"
 
 	"github.com/kucjac/gentools/types"
 )
 
 func TestZero(t *testing.T) {
-	pkgs, err := LoadPackages(LoadConfig{
+	pkgs, _, err := LoadPackages(LoadConfig{
 		Paths:      []string{"."},
 		PkgNames:   nil,
 		BuildFlags: nil,
@@ -135,11 +136,14 @@ func TestParse(t *testing.T) {
 		WithComments: true,
 		Verbose:      true,
 	}
-	pkgs, err := LoadPackages(cfg)
+	pkgs, diagnostics, err := LoadPackages(cfg)
 	if err != nil {
 		t.Errorf("Parsing packages failed: %v", err)
 		return
 	}
+	if diagnostics.HasErrors() {
+		t.Errorf("Parsing packages resulted with errors:\n%s", diagnostics.Errors())
+	}
 
 	pkg, ok := pkgs.PackageByPath(testCasesPkgPath)
 	if !ok {
@@ -234,9 +238,17 @@ func TestParse(t *testing.T) {
 
 	t.Run("NotEmpty", testNotEmptyInterface(notEmptyInterface, pkgs))
 
+	t.Run("Embedded", testEmbeddedInterfaces(notEmptyInterface, inheritMeInterface, pkgs))
+
 	t.Run("Foo", testFoo(fooStruct, enum, barStruct))
 
 	t.Run("Bar", testBar(barStruct, pkgs, notEmpty))
+
+	t.Run("Generics", testGenerics(pkg, fooStruct))
+
+	t.Run("Constraints", testConstraints(pkg, fooID, fooStruct))
+
+	t.Run("Positions", testPositions(pkg, fooStruct, fooID))
 }
 
 func testMultiPointerInlineStruct(pkgs types.PackageMap, pkg *types.Package) func(t *testing.T) {
@@ -829,3 +841,363 @@ func testArrayWrapper(pkg *types.Package) func(t *testing.T) {
 		}
 	}
 }
+
+func testGenerics(pkg *types.Package, fooStruct *types.Struct) func(t *testing.T) {
+	return func(t *testing.T) {
+		list, ok := pkg.GetStruct("List")
+		if !ok {
+			t.Fatal("generic List struct not found")
+		}
+		if !list.IsGeneric() {
+			t.Fatal("List is expected to be generic")
+		}
+		if len(list.TypeParams) != 1 {
+			t.Fatalf("List should have exactly one type param but have: %d", len(list.TypeParams))
+		}
+		param := list.TypeParams[0]
+		if param.ParamName != "T" || param.Kind() != types.KindTypeParam {
+			t.Errorf("List type param expected to be 'T' of KindTypeParam but is: %s, %s", param.ParamName, param.Kind())
+		}
+		if param.Constraint != types.Any {
+			t.Errorf("List type param constraint expected to be 'any' but is: %v", param.Constraint)
+		}
+		if name := list.Name(false, ""); name != "List[T]" {
+			t.Errorf("List name expected to be 'List[T]' but is: %s", name)
+		}
+		if len(list.Fields) != 2 {
+			t.Fatalf("List should have two fields but have: %d", len(list.Fields))
+		}
+		if !list.Fields[0].Type.Equal(types.PointerTo(list)) {
+			t.Errorf("List 'next' field expected to be a pointer to the List but is: %v", list.Fields[0].Type)
+		}
+		if list.Fields[1].Type != param {
+			t.Errorf("List 'Value' field expected to be of type T but is: %v", list.Fields[1].Type)
+		}
+		if len(list.Methods) != 1 {
+			t.Fatalf("List should have exactly one method but have: %d", len(list.Methods))
+		}
+		push := list.Methods[0]
+		if push.Receiver == nil || push.Receiver.String() != "(l *List[T])" {
+			t.Errorf("List Push receiver doesn't match: %v", push.Receiver)
+		}
+		if len(push.In) != 1 || push.In[0].Type != param {
+			t.Errorf("List Push input should be of type T")
+		}
+
+		set, ok := pkg.GetAlias("Set")
+		if !ok {
+			t.Fatal("generic Set alias not found")
+		}
+		if len(set.TypeParams) != 1 || set.TypeParams[0].Constraint != types.Comparable {
+			t.Fatal("Set is expected to have a single comparable type param")
+		}
+		if len(set.Methods) != 1 || set.Methods[0].FuncName != "Has" {
+			t.Fatal("Set is expected to have a Has method")
+		}
+
+		getter, ok := pkg.GetInterfaceType("Getter")
+		if !ok {
+			t.Fatal("generic Getter interface not found")
+		}
+		if len(getter.TypeParams) != 1 || len(getter.Methods) != 1 {
+			t.Fatal("Getter is expected to have a single type param and a single method")
+		}
+		if param.Equal(getter.TypeParams[0]) {
+			t.Error("List type param 'T' should not be equal to the Getter type param 'T'")
+		}
+
+		mapFunc, ok := pkg.GetFunction("Map")
+		if !ok {
+			t.Fatal("generic Map function not found")
+		}
+		if len(mapFunc.TypeParams) != 2 {
+			t.Fatalf("Map should have two type params but have: %d", len(mapFunc.TypeParams))
+		}
+		if decl := mapFunc.TypeParams.Declaration(false, ""); decl != "[T any, U any]" {
+			t.Errorf("Map type params declaration doesn't match: %s", decl)
+		}
+		if !mapFunc.In[0].Type.Equal(types.SliceOf(mapFunc.TypeParams[0])) {
+			t.Errorf("Map first input expected to be []T but is: %v", mapFunc.In[0].Type)
+		}
+
+		sum, ok := pkg.GetFunction("Sum")
+		if !ok {
+			t.Fatal("generic Sum function not found")
+		}
+		if len(sum.TypeParams) != 1 || sum.TypeParams[0].Constraint.Name(false, "") != "Number" {
+			t.Fatal("Sum is expected to have a single type param with the Number constraint")
+		}
+
+		g, ok := pkg.GetStruct("Generics")
+		if !ok {
+			t.Fatal("Generics struct not found")
+		}
+		if len(g.Fields) != 5 {
+			t.Fatalf("Generics should have five fields but have: %d", len(g.Fields))
+		}
+
+		ints, ok := g.Fields[0].Type.(*types.Struct)
+		if !ok {
+			t.Fatalf("Generics 'Ints' field expected to be a struct but is: %T", g.Fields[0].Type)
+		}
+		if ints.Origin != list {
+			t.Error("List[int] origin should be the generic List")
+		}
+		if name := ints.Name(true, ""); name != "testcases.List[int]" {
+			t.Errorf("List[int] name doesn't match: %s", name)
+		}
+		if zero := ints.Zero(false, ""); zero != "List[int]{}" {
+			t.Errorf("List[int] zero value doesn't match: %s", zero)
+		}
+		if fn := ints.FullName(); fn != pkg.Path+"/List[int]" {
+			t.Errorf("List[int] full name doesn't match: %s", fn)
+		}
+		if ints.Fields[1].Type != types.Int {
+			t.Errorf("List[int] 'Value' field expected to be an int but is: %v", ints.Fields[1].Type)
+		}
+		if !ints.Fields[0].Type.Equal(types.PointerTo(ints)) {
+			t.Errorf("List[int] 'next' field expected to be *List[int] but is: %v", ints.Fields[0].Type)
+		}
+		if ints.Equal(list) {
+			t.Error("List[int] should not be equal to generic List")
+		}
+
+		keys, ok := g.Fields[1].Type.(*types.Alias)
+		if !ok {
+			t.Fatalf("Generics 'Keys' field expected to be an alias but is: %T", g.Fields[1].Type)
+		}
+		if zero := keys.Zero(false, ""); zero != "Set[string](nil)" {
+			t.Errorf("Set[string] zero value doesn't match: %s", zero)
+		}
+		if keys.Kind() != types.KindMap {
+			t.Errorf("Set[string] kind expected to be a map but is: %s", keys.Kind())
+		}
+
+		pair := g.Fields[2].Type.Elem().(*types.Struct)
+		if name := pair.Name(false, ""); name != "Pair[string, Foo]" {
+			t.Errorf("Pair[string, Foo] name doesn't match: %s", name)
+		}
+		if pair.Fields[1].Type != fooStruct {
+			t.Errorf("Pair[string, Foo] 'Value' field expected to be Foo but is: %v", pair.Fields[1].Type)
+		}
+
+		gt, ok := g.Fields[3].Type.(*types.Interface)
+		if !ok {
+			t.Fatalf("Generics 'Getter' field expected to be an interface but is: %T", g.Fields[3].Type)
+		}
+		if name := gt.Name(false, ""); name != "Getter[FooID]" {
+			t.Errorf("Getter[FooID] name doesn't match: %s", name)
+		}
+		if gt.Methods[0].Out[0].Type.Name(false, "") != "FooID" {
+			t.Errorf("Getter[FooID] method output doesn't match: %v", gt.Methods[0].Out[0].Type)
+		}
+
+		indexed := g.Fields[4].Type.(*types.Map)
+		if name := indexed.Value.Name(false, ""); name != "Pair[int, []byte]" {
+			t.Errorf("Pair[int, []byte] name doesn't match: %s", name)
+		}
+	}
+}
+
+func testConstraints(pkg *types.Package, fooID types.Type, fooStruct *types.Struct) func(t *testing.T) {
+	return func(t *testing.T) {
+		number, ok := pkg.GetInterfaceType("Number")
+		if !ok {
+			t.Fatal("Number interface not found")
+		}
+		if !number.IsConstraintOnly() {
+			t.Error("Number should be a constraint only interface")
+		}
+		if number.IsEmpty() {
+			t.Error("Number should not be an empty interface")
+		}
+		if len(number.Unions) != 1 {
+			t.Fatalf("Number should have exactly one union but have: %d", len(number.Unions))
+		}
+		terms := number.Unions[0].Terms
+		if len(terms) != 3 {
+			t.Fatalf("Number union should have three terms but have: %d", len(terms))
+		}
+		for i, expected := range []types.Type{types.Int, types.Int64, types.Float64} {
+			if !terms[i].Tilde {
+				t.Errorf("Number term: %d expected to be an approximation term", i)
+			}
+			if terms[i].Type != expected {
+				t.Errorf("Number term: %d expected to be %s but is: %s", i, expected, terms[i].Type)
+			}
+		}
+		if u := number.Unions[0].Name(false, ""); u != "~int | ~int64 | ~float64" {
+			t.Errorf("Number union name doesn't match: %s", u)
+		}
+
+		// FooID is a defined type with the underlying int64, thus it matches the '~int64' term.
+		for _, tp := range []types.Type{types.Int, types.Float64, fooID} {
+			if !number.TypeSetContains(tp) {
+				t.Errorf("Number type set should contain: %s", tp)
+			}
+		}
+		for _, tp := range []types.Type{types.String, types.Int32, fooStruct} {
+			if number.TypeSetContains(tp) {
+				t.Errorf("Number type set should not contain: %s", tp)
+			}
+		}
+
+		numeric, ok := pkg.GetInterfaceType("Numeric")
+		if !ok {
+			t.Fatal("Numeric interface not found")
+		}
+		if !numeric.TypeSetContains(types.Int8) {
+			t.Error("Numeric type set should contain int8 from the embedded Integer constraint")
+		}
+		if !numeric.TypeSetContains(types.Float32) {
+			t.Error("Numeric type set should contain float32")
+		}
+		if numeric.TypeSetContains(types.Uint) {
+			t.Error("Numeric type set should not contain uint")
+		}
+
+		key, ok := pkg.GetInterfaceType("Key")
+		if !ok {
+			t.Fatal("Key interface not found")
+		}
+		if !key.IsConstraintOnly() {
+			t.Error("Key embeds comparable thus it should be a constraint only interface")
+		}
+		if len(key.Methods) != 1 || key.Methods[0].FuncName != "String" {
+			t.Fatal("Key should have a String method")
+		}
+		if key.TypeSetContains(types.String) {
+			t.Error("Key type set should not contain string that doesn't implement fmt.Stringer")
+		}
+
+		maxFunc, ok := pkg.GetFunction("Max")
+		if !ok {
+			t.Fatal("Max function not found")
+		}
+		constraint, ok := maxFunc.TypeParams[0].Constraint.(*types.Interface)
+		if !ok {
+			t.Fatalf("Max type param constraint should be an interface but is: %T", maxFunc.TypeParams[0].Constraint)
+		}
+		if !constraint.Implicit {
+			t.Error("Max type param constraint should be an implicit interface")
+		}
+		if decl := maxFunc.TypeParams.Declaration(false, ""); decl != "[T ~int | ~string]" {
+			t.Errorf("Max type params declaration doesn't match: %s", decl)
+		}
+	}
+}
+
+func testEmbeddedInterfaces(notEmpty, inheritMe *types.Interface, pkgs types.PackageMap) func(t *testing.T) {
+	return func(t *testing.T) {
+		if len(notEmpty.Embedded) != 1 {
+			t.Fatalf("NotEmpty should embed exactly one interface but embeds: %d", len(notEmpty.Embedded))
+		}
+		if notEmpty.Embedded[0] != inheritMe {
+			t.Errorf("NotEmpty should embed InheritMe but embeds: %v", notEmpty.Embedded[0])
+		}
+		if !notEmpty.IsEmbedded(inheritMe) {
+			t.Error("InheritMe should be embedded in the NotEmpty")
+		}
+		if len(notEmpty.ExplicitMethods) != 1 {
+			t.Fatalf("NotEmpty should declare exactly one method but declares: %d", len(notEmpty.ExplicitMethods))
+		}
+		call := notEmpty.ExplicitMethods[0]
+		if call.FuncName != "Call" {
+			t.Errorf("NotEmpty explicit method should be 'Call' but is: %s", call.FuncName)
+		}
+		if call.Comment != "Call executes the call with given options.\n" {
+			t.Errorf("NotEmpty Call explicit method comment doesn't match: '%s'", call.Comment)
+		}
+		if m, ok := notEmpty.Method("Call"); !ok || m.Comment != call.Comment {
+			t.Error("NotEmpty Call method comment doesn't match the explicit method comment")
+		}
+		if m, ok := notEmpty.Method("Inherited"); !ok || m.Comment != "" {
+			t.Error("NotEmpty Inherited method should not take the comment of the Call method")
+		}
+
+		tp, ok := pkgs.TypeOf("io.ReadCloser", nil)
+		if !ok {
+			t.Fatal("io.ReadCloser not found")
+		}
+		readCloser := tp.(*types.Interface)
+		if len(readCloser.Methods) != 2 {
+			t.Errorf("io.ReadCloser should have two methods but have: %d", len(readCloser.Methods))
+		}
+		if len(readCloser.ExplicitMethods) != 0 {
+			t.Errorf("io.ReadCloser should not declare any methods but declares: %d", len(readCloser.ExplicitMethods))
+		}
+		if len(readCloser.Embedded) != 2 {
+			t.Fatalf("io.ReadCloser should embed two interfaces but embeds: %d", len(readCloser.Embedded))
+		}
+		for i, name := range []string{"io.Reader", "io.Closer"} {
+			if en := readCloser.Embedded[i].Name(true, ""); en != name {
+				t.Errorf("io.ReadCloser embedded interface: %d should be %s but is: %s", i, name, en)
+			}
+		}
+	}
+}
+
+func testPositions(pkg *types.Package, fooStruct *types.Struct, fooID types.Type) func(t *testing.T) {
+	return func(t *testing.T) {
+		pos := fooStruct.Pos
+		if !pos.IsValid() {
+			t.Fatal("Foo position is not valid")
+		}
+		if !strings.HasSuffix(pos.Filename, "models.go") {
+			t.Errorf("Foo position filename is expected to be models.go but is: %s", pos.Filename)
+		}
+		if !pos.HasRange() {
+			t.Fatal("Foo position should have declaration range defined")
+		}
+		src, err := pos.Source()
+		if err != nil {
+			t.Fatalf("getting Foo source failed: %v", err)
+		}
+		if !strings.HasPrefix(string(src), "type Foo struct") {
+			t.Errorf("Foo source doesn't start with its spec: %s", src)
+		}
+
+		// The source should be found by the line and column even without the range defined.
+		noRange := pos
+		noRange.Offset, noRange.End = 0, 0
+		src2, err := noRange.Source()
+		if err != nil {
+			t.Fatalf("getting Foo source without range failed: %v", err)
+		}
+		if string(src) != string(src2) {
+			t.Errorf("Foo source mismatch: %s != %s", src, src2)
+		}
+
+		for _, field := range fooStruct.Fields {
+			if field.Pos.Line <= pos.Line || field.Pos.Offset < pos.Offset || field.Pos.End > pos.End {
+				t.Errorf("Foo.%s field position: %s should be within Foo declaration", field.Name, field.Pos)
+			}
+		}
+
+		fooIDAlias, ok := fooID.(*types.Alias)
+		if !ok {
+			t.Fatalf("FooID is expected to be an alias but is: %T", fooID)
+		}
+		for _, method := range fooIDAlias.Methods {
+			if !method.Pos.IsValid() {
+				t.Errorf("FooID method: %s position is not valid", method.FuncName)
+			}
+			src, err = method.Pos.Source()
+			if err != nil {
+				t.Errorf("getting FooID.%s source failed: %v", method.FuncName, err)
+				continue
+			}
+			if !strings.HasPrefix(string(src), "func (f") {
+				t.Errorf("FooID.%s source doesn't start with the func declaration: %s", method.FuncName, src)
+			}
+		}
+
+		decl, ok := pkg.Declarations["EnumeratedOne"]
+		if !ok {
+			t.Fatal("EnumeratedOne declaration not found")
+		}
+		if !decl.Pos.IsValid() || decl.Pos.Column == 0 {
+			t.Errorf("EnumeratedOne declaration position is not valid: %s", decl.Pos)
+		}
+	}
+}
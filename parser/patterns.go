@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// MatchPackages gets the import paths of the packages matched by the LoadConfig.Paths and LoadConfig.PkgNames.
+// Both of them might contain the go list patterns i.e.: './...', 'std' or 'github.com/my/module/...'.
+func MatchPackages(cfg LoadConfig) ([]string, error) {
+	return MatchPackagesContext(context.Background(), cfg)
+}
+
+// MatchPackagesContext gets the import paths of the packages matched by the LoadConfig.Paths
+// and LoadConfig.PkgNames. The matching is stopped when given context is done.
+func MatchPackagesContext(ctx context.Context, cfg LoadConfig) ([]string, error) {
+	p := &packageMap{}
+	if _, err := p.packageNames(ctx, &cfg); err != nil {
+		return nil, err
+	}
+	return p.matched, nil
+}
+
+// isPackagePattern checks if given path or package name is the go list pattern, that might match multiple packages.
+func isPackagePattern(name string) bool {
+	switch name {
+	case "all", "std", "cmd":
+		return true
+	}
+	return strings.Contains(name, "...")
+}
+
+// absPathPattern resolves the relative directory pattern i.e.: './...' against the working directory of the loader.
+func absPathPattern(cfg *LoadConfig, path string) (string, error) {
+	if !strings.Contains(path, "...") || filepath.IsAbs(path) {
+		return path, nil
+	}
+	return cfg.absPath(path)
+}
+
+// packageNames gets the import paths of the packages defined by the config Paths and PkgNames. The patterns and
+// the directories are resolved by the go list into the import paths of the matched packages, thus the directories
+// with no go files are not matched. The sorted import paths are stored as matched packages.
+func (p *packageMap) packageNames(ctx context.Context, cfg *LoadConfig) ([]string, error) {
+	var patterns []string
+	for _, path := range cfg.Paths {
+		if isPackagePattern(path) {
+			pattern, err := absPathPattern(cfg, path)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, pattern)
+			continue
+		}
+		dir, err := cfg.absPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.overlayHasGoFile(dir) {
+			// The go list matches the nonexistent directory as the package with an error.
+			if _, err = os.Stat(dir); err != nil {
+				return nil, err
+			}
+		}
+		patterns = append(patterns, dir)
+	}
+	pkgNames := make([]string, 0, len(cfg.PkgNames))
+	for _, pkgName := range cfg.PkgNames {
+		if isPackagePattern(pkgName) {
+			patterns = append(patterns, pkgName)
+		} else {
+			pkgNames = append(pkgNames, pkgName)
+		}
+	}
+
+	if len(patterns) != 0 {
+		matched, err := matchPackages(ctx, cfg, packages.NeedName, patterns)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 && len(pkgNames) == 0 {
+			return nil, fmt.Errorf("no packages matched the patterns: %s", strings.Join(patterns, " "))
+		}
+		for _, pkg := range matched {
+			pkgNames = append(pkgNames, pkg.PkgPath)
+		}
+	}
+
+	unique := make(map[string]struct{}, len(pkgNames))
+	result := pkgNames[:0]
+	for _, pkgName := range pkgNames {
+		if _, ok := unique[pkgName]; ok {
+			continue
+		}
+		unique[pkgName] = struct{}{}
+		result = append(result, pkgName)
+	}
+	p.matched = append([]string(nil), result...)
+	sort.Strings(p.matched)
+	return result, nil
+}
+
+// matchPackages gets the packages matched by given go list patterns, loaded in given mode. The matched directories
+// with no go files for any build constraints are not the packages, and thus are skipped.
+func matchPackages(ctx context.Context, cfg *LoadConfig, mode packages.LoadMode, patterns []string) ([]*packages.Package, error) {
+	overlay, err := cfg.absOverlay()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := cfg.load(&packages.Config{
+		Context:    ctx,
+		Mode:       mode | packages.NeedFiles,
+		BuildFlags: cfg.BuildFlags,
+		Overlay:    overlay,
+	}, patterns...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	matched := pkgs[:0]
+	for _, pkg := range pkgs {
+		if pkg.PkgPath != "" && (len(pkg.GoFiles) != 0 || len(pkg.IgnoredFiles) != 0) {
+			matched = append(matched, pkg)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/kucjac/gentools/internal/astutil"
+	"github.com/kucjac/gentools/types"
+)
+
+// indexDeclarationNodes maps the position of all declared identifiers to the ast nodes that declares them.
+// This way the position of the go/types objects could be extended with the byte range of its declaration.
+func (r *rootPackage) indexDeclarationNodes() {
+	r.declNodes = map[token.Pos]ast.Node{}
+	for _, file := range r.pkgPkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			for _, ident := range astutil.DeclaredIdents(n) {
+				// The outermost declaration node is visited first.
+				if _, ok := r.declNodes[ident.Pos()]; !ok {
+					r.declNodes[ident.Pos()] = n
+				}
+			}
+			return true
+		})
+	}
+}
+
+// objectPosition gets the source position of given object. The byte offset range of the declaration is defined
+// only if the package syntax was loaded.
+func (r *rootPackage) objectPosition(obj gotypes.Object) types.Position {
+	root := r
+	if rp, ok := r.rootPackages[obj.Pkg()]; ok {
+		root = rp
+	}
+	return root.tokenPosition(obj.Pos())
+}
+
+// typeExpr gets the source type expression of given object i.e.: the type of the struct field. The result is nil
+// if the object is not declared with its own type expression.
+func (r *rootPackage) typeExpr(obj gotypes.Object) ast.Expr {
+	n := r.declarationNode(obj)
+	if gd, ok := n.(*ast.GenDecl); ok && len(gd.Specs) == 1 {
+		n = gd.Specs[0]
+	}
+	switch nt := n.(type) {
+	case *ast.Field:
+		return nt.Type
+	case *ast.TypeSpec:
+		return nt.Type
+	}
+	return nil
+}
+
+// declarationNode gets the ast node declaring given object. If the package syntax is not loaded, the file
+// of the object is parsed on demand.
+func (r *rootPackage) declarationNode(obj gotypes.Object) ast.Node {
+	root := r
+	if rp, ok := r.rootPackages[obj.Pkg()]; ok {
+		root = rp
+	}
+	if len(root.pkgPkg.Syntax) != 0 {
+		return root.declNodes[obj.Pos()]
+	}
+	if r.pkgPkg.Fset == nil || !obj.Pos().IsValid() {
+		return nil
+	}
+	tp := r.pkgPkg.Fset.Position(obj.Pos())
+	nodes, ok := r.fileNodes[tp.Filename]
+	if !ok {
+		nodes = r.indexFileNodes(tp.Filename)
+		if r.fileNodes == nil {
+			r.fileNodes = map[string]map[int]ast.Node{}
+		}
+		r.fileNodes[tp.Filename] = nodes
+	}
+	return nodes[tp.Offset]
+}
+
+// indexFileNodes parses given file, and maps the offsets of its declared identifiers to the ast nodes
+// that declares them.
+func (r *rootPackage) indexFileNodes(fileName string) map[int]ast.Node {
+	src, err := r.readSource(fileName)
+	if err != nil {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	nodes := map[int]ast.Node{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		for _, ident := range astutil.DeclaredIdents(n) {
+			offset := fset.Position(ident.Pos()).Offset
+			if _, ok := nodes[offset]; !ok {
+				nodes[offset] = n
+			}
+		}
+		return true
+	})
+	return nodes
+}
+
+// tokenPosition gets the source position of given token position within the root package.
+func (r *rootPackage) tokenPosition(p token.Pos) types.Position {
+	fset := r.pkgPkg.Fset
+	if fset == nil || !p.IsValid() {
+		return types.Position{}
+	}
+	tp := fset.Position(p)
+	pos := types.Position{Filename: tp.Filename, Line: tp.Line, Column: tp.Column, Overlay: r.pkgMap.overlay}
+	if n, ok := r.declNodes[p]; ok {
+		pos.Offset = fset.Position(n.Pos()).Offset
+		pos.End = fset.Position(n.End()).Offset
+	}
+	return pos
+}
+
+func setTypePosition(tp types.Type, pos types.Position) {
+	switch t := tp.(type) {
+	case *types.Struct:
+		t.Pos = pos
+	case *types.Interface:
+		t.Pos = pos
+	case *types.Alias:
+		t.Pos = pos
+	case *types.Function:
+		t.Pos = pos
+	}
+}
@@ -0,0 +1,439 @@
+package parser
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// ChangeKind is the kind of the type change found by reloading the packages.
+type ChangeKind int
+
+// Enumerated change kinds.
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+// String implements fmt.Stringer interface.
+func (c ChangeKind) String() string {
+	switch c {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is the change of the named type or function within the reloaded package.
+type Change struct {
+	PkgPath string
+	Name    string
+	Kind    ChangeKind
+	// Type is the reloaded type. For the removed types it is the type before the reload.
+	Type types.Type
+	// Members are the changes of the fields and methods of the modified type.
+	Members []MemberChange
+}
+
+// String implements fmt.Stringer interface.
+func (c Change) String() string {
+	return c.Kind.String() + ": " + c.PkgPath + "." + c.Name
+}
+
+// MemberChange is the change of the struct field or the method of the modified type.
+type MemberChange struct {
+	Name string
+	// Method states if the member is a method.
+	Method bool
+	Kind   ChangeKind
+}
+
+// Changes is the list of the type changes sorted by the package path and the type name.
+type Changes []Change
+
+// String implements fmt.Stringer interface. Each change is written in a separate line.
+func (c Changes) String() string {
+	lines := make([]string, len(c))
+	for i, change := range c {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ReloadPackages parses again the packages defined in the config, along with all the packages from the map
+// that depends on them. The reloaded packages replaces the previous ones in the map, and the types of dependent
+// packages are rebuilt to reference them. The function returns the named types and functions that were added,
+// removed or modified by the reload. The changes of the dependent packages caused only by the types they refer to
+// are not reported.
+func ReloadPackages(p types.PackageMap, cfg LoadConfig) (Changes, Diagnostics, error) {
+	return ReloadPackagesContext(context.Background(), p, cfg)
+}
+
+// ReloadPackagesContext parses again the packages defined in the config, along with all the packages from the map
+// that depends on them. If the reload fails or given context is done, the previous packages are restored,
+// so that the map is left unchanged.
+func ReloadPackagesContext(ctx context.Context, p types.PackageMap, cfg LoadConfig) (Changes, Diagnostics, error) {
+	pm := &packageMap{pkgMap: p}
+	pkgNames, err := pm.packageNames(ctx, &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgNames = dependentPackages(p, pkgNames)
+
+	previous := make(map[string]*types.Package, len(pkgNames))
+	for _, pkgName := range pkgNames {
+		if pkg, ok := p[pkgName]; ok {
+			previous[pkgName] = pkg
+			delete(p, pkgName)
+		}
+	}
+	if err = pm.loadAndParse(ctx, &cfg, pkgNames...); err != nil {
+		for path, pkg := range previous {
+			p[path] = pkg
+		}
+		return nil, pm.diagnostics, err
+	}
+	sortDiagnostics(pm.diagnostics)
+
+	var changes Changes
+	for _, pkgName := range pkgNames {
+		pkg, ok := p[pkgName]
+		if !ok {
+			continue
+		}
+		changes = append(changes, packageChanges(previous[pkgName], pkg)...)
+	}
+	return changes, pm.diagnostics, nil
+}
+
+// dependentPackages gets the sorted paths of given packages along with the packages from the map that
+// directly or indirectly imports them.
+func dependentPackages(p types.PackageMap, pkgNames []string) []string {
+	importedBy := map[string][]string{}
+	for path, pkg := range p {
+		for _, imp := range pkg.Imports {
+			importedBy[imp] = append(importedBy[imp], path)
+		}
+	}
+	result := map[string]struct{}{}
+	var visit func(path string)
+	visit = func(path string) {
+		if _, ok := result[path]; ok {
+			return
+		}
+		result[path] = struct{}{}
+		for _, dependent := range importedBy[path] {
+			visit(dependent)
+		}
+	}
+	for _, pkgName := range pkgNames {
+		visit(pkgName)
+	}
+	paths := make([]string, 0, len(result))
+	for path := range result {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// packageChanges compares the named types and functions of the previous and reloaded package.
+// The previous package is nil if the package was not loaded before.
+func packageChanges(previous, reloaded *types.Package) Changes {
+	var prevTypes map[string]types.Type
+	if previous != nil {
+		prevTypes = previous.Types
+	}
+	names := map[string]struct{}{}
+	for name := range prevTypes {
+		names[name] = struct{}{}
+	}
+	for name := range reloaded.Types {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes Changes
+	for _, name := range sorted {
+		prev, hadPrev := prevTypes[name]
+		cur, hasCur := reloaded.Types[name]
+		change := Change{PkgPath: reloaded.Path, Name: name, Type: cur}
+		switch {
+		case !hadPrev:
+			change.Kind = ChangeAdded
+		case !hasCur:
+			change.Kind, change.Type = ChangeRemoved, prev
+		case typeDefinition(prev) != typeDefinition(cur):
+			change.Kind, change.Members = ChangeModified, memberChanges(prev, cur)
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// memberChanges compares the fields and methods of the previous and reloaded type.
+func memberChanges(previous, reloaded types.Type) []MemberChange {
+	prevFields, prevMethods := typeMembers(previous)
+	curFields, curMethods := typeMembers(reloaded)
+	changes := diffMembers(prevFields, curFields, false)
+	return append(changes, diffMembers(prevMethods, curMethods, true)...)
+}
+
+// typeMembers gets the definitions of the fields and methods of given type by their names.
+func typeMembers(t types.Type) (fields, methods map[string]string) {
+	fields, methods = map[string]string{}, map[string]string{}
+	var list []types.Function
+	switch x := t.(type) {
+	case *types.Struct:
+		for _, field := range x.Fields {
+			sb := &strings.Builder{}
+			writeField(sb, field)
+			fields[field.Name] = sb.String()
+		}
+		list = x.Methods
+	case *types.Interface:
+		list = x.Methods
+	case *types.Alias:
+		list = x.Methods
+	}
+	for i := range list {
+		sb := &strings.Builder{}
+		writeFunction(sb, &list[i])
+		writeBuilds(sb, list[i].Builds, list[i].BuildConstraint)
+		methods[list[i].FuncName] = sb.String()
+	}
+	return fields, methods
+}
+
+func diffMembers(previous, reloaded map[string]string, method bool) []MemberChange {
+	names := make([]string, 0, len(previous)+len(reloaded))
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range reloaded {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []MemberChange
+	for _, name := range names {
+		prev, hadPrev := previous[name]
+		cur, hasCur := reloaded[name]
+		var kind ChangeKind
+		switch {
+		case !hadPrev:
+			kind = ChangeAdded
+		case !hasCur:
+			kind = ChangeRemoved
+		case prev != cur:
+			kind = ChangeModified
+		default:
+			continue
+		}
+		changes = append(changes, MemberChange{Name: name, Method: method, Kind: kind})
+	}
+	return changes
+}
+
+// typeDefinition gets the string definition of the named type used to detect its changes. The named types it
+// refers to are written by their full names, and the source positions are omitted, thus neither the changes
+// of the referenced types nor moving the declaration within the file changes the definition.
+func typeDefinition(t types.Type) string {
+	sb := &strings.Builder{}
+	writeTypeDefinition(sb, t)
+	return sb.String()
+}
+
+func writeTypeDefinition(sb *strings.Builder, t types.Type) {
+	switch x := t.(type) {
+	case *types.Struct:
+		sb.WriteString("struct")
+		writeTypeParams(sb, x.TypeParams)
+		sb.WriteString(strconv.Quote(x.Comment))
+		writeMarkers(sb, x.Markers)
+		sb.WriteRune('{')
+		for _, field := range x.Fields {
+			sb.WriteString(field.Name + " ")
+			writeField(sb, field)
+			sb.WriteRune(';')
+		}
+		sb.WriteRune('}')
+		writeMethods(sb, x.Methods)
+		writeBuilds(sb, x.Builds, x.BuildConstraint)
+	case *types.Interface:
+		sb.WriteString("interface")
+		writeTypeParams(sb, x.TypeParams)
+		sb.WriteString(strconv.Quote(x.Comment))
+		writeMarkers(sb, x.Markers)
+		sb.WriteRune('{')
+		for _, embedded := range x.Embedded {
+			writeTypeReference(sb, embedded)
+			sb.WriteRune(';')
+		}
+		for _, union := range x.Unions {
+			for _, term := range union.Terms {
+				if term.Tilde {
+					sb.WriteRune('~')
+				}
+				writeTypeReference(sb, term.Type)
+				sb.WriteRune('|')
+			}
+			sb.WriteRune(';')
+		}
+		sb.WriteRune('}')
+		writeMethods(sb, x.Methods)
+		writeBuilds(sb, x.Builds, x.BuildConstraint)
+	case *types.Alias:
+		sb.WriteString("type")
+		writeTypeParams(sb, x.TypeParams)
+		if x.TypeAlias {
+			sb.WriteString(" =")
+		}
+		sb.WriteString(strconv.Quote(x.Comment))
+		writeMarkers(sb, x.Markers)
+		sb.WriteRune(' ')
+		writeTypeReference(sb, x.Type)
+		writeMethods(sb, x.Methods)
+		writeBuilds(sb, x.Builds, x.BuildConstraint)
+	case *types.Function:
+		writeFunction(sb, x)
+		writeBuilds(sb, x.Builds, x.BuildConstraint)
+	default:
+		writeTypeReference(sb, t)
+	}
+}
+
+// writeTypeReference writes the reference to given type. The named types are written by their full names,
+// whereas the anonymous ones by their definition.
+func writeTypeReference(sb *strings.Builder, t types.Type) {
+	switch x := t.(type) {
+	case nil:
+		sb.WriteString("nil")
+	case *types.Struct:
+		if x.TypeName == "" {
+			writeTypeDefinition(sb, x)
+			return
+		}
+		sb.WriteString(x.FullName())
+	case *types.Interface:
+		if x.InterfaceName == "" {
+			writeTypeDefinition(sb, x)
+			return
+		}
+		sb.WriteString(x.FullName())
+	case *types.Function:
+		if x.FuncName == "" {
+			writeFunction(sb, x)
+			return
+		}
+		sb.WriteString(x.FullName())
+	case *types.Pointer:
+		sb.WriteRune('*')
+		writeTypeReference(sb, x.PointedType)
+	case *types.Array:
+		if x.ArrayKind == types.KindSlice {
+			sb.WriteString("[]")
+		} else {
+			sb.WriteString("[" + strconv.Itoa(x.ArraySize) + "]")
+		}
+		writeTypeReference(sb, x.Type)
+	case *types.Map:
+		sb.WriteString("map[")
+		writeTypeReference(sb, x.Key)
+		sb.WriteRune(']')
+		writeTypeReference(sb, x.Value)
+	case *types.Chan:
+		sb.WriteString("chan" + strconv.Itoa(int(x.Dir)) + " ")
+		writeTypeReference(sb, x.Type)
+	case *types.TypeParam:
+		sb.WriteString(x.ParamName)
+	default:
+		sb.WriteString(t.FullName())
+	}
+}
+
+func writeField(sb *strings.Builder, field types.StructField) {
+	writeTypeReference(sb, field.Type)
+	sb.WriteString(" " + strconv.Quote(string(field.Tag)) + " " + strconv.FormatBool(field.Embedded))
+	sb.WriteString(" " + strconv.Quote(field.Comment) + " " + strconv.Quote(field.LineComment))
+	writeMarkers(sb, field.Markers)
+}
+
+func writeFunction(sb *strings.Builder, f *types.Function) {
+	sb.WriteString("func ")
+	if f.Receiver != nil {
+		sb.WriteString("(" + f.Receiver.Name + " ")
+		writeTypeReference(sb, f.Receiver.Type)
+		sb.WriteString(") ")
+	}
+	sb.WriteString(f.FuncName)
+	writeTypeParams(sb, f.TypeParams)
+	sb.WriteString(strconv.Quote(f.Comment) + strconv.Quote(f.LineComment))
+	writeMarkers(sb, f.Markers)
+	for _, params := range [2][]types.FuncParam{f.In, f.Out} {
+		sb.WriteRune('(')
+		for _, param := range params {
+			sb.WriteString(param.Name + " ")
+			writeTypeReference(sb, param.Type)
+			sb.WriteRune(',')
+		}
+		sb.WriteRune(')')
+	}
+	if f.Variadic {
+		sb.WriteString("...")
+	}
+}
+
+func writeMethods(sb *strings.Builder, methods []types.Function) {
+	for i := range methods {
+		sb.WriteRune(';')
+		writeFunction(sb, &methods[i])
+		writeBuilds(sb, methods[i].Builds, methods[i].BuildConstraint)
+	}
+}
+
+func writeTypeParams(sb *strings.Builder, params types.TypeParams) {
+	if len(params) == 0 {
+		return
+	}
+	sb.WriteRune('[')
+	for _, param := range params {
+		sb.WriteString(param.ParamName + " ")
+		writeTypeReference(sb, param.Constraint)
+		sb.WriteRune(',')
+	}
+	sb.WriteRune(']')
+}
+
+// writeMarkers writes the markers by their source text, as the directive like markers are not a part of the comments.
+func writeMarkers(sb *strings.Builder, markers types.Markers) {
+	for _, m := range markers {
+		sb.WriteString(" @" + strconv.Quote(m.Text))
+	}
+}
+
+func writeBuilds(sb *strings.Builder, builds types.BuildContexts, expr string) {
+	for _, bc := range builds {
+		sb.WriteString(" +" + bc.String())
+	}
+	if expr != "" {
+		sb.WriteString(" //go:build " + expr)
+	}
+}
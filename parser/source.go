@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// SourceModulePath is the module path of the sources loaded by the LoadSource and LoadTxtar functions,
+// whose files doesn't define their own go.mod file.
+const SourceModulePath = "example.com/source"
+
+// LoadSource parses the packages of given Go source files. The files are mapped by their slash separated paths
+// relative to the module root i.e.: 'foo/foo.go'. If the files doesn't define the 'go.mod' file, the module
+// SourceModulePath is used. The files are written into a temporary directory, which is removed after the load,
+// thus the positions of the result doesn't point to the existing files, and the result could not be reloaded
+// nor watched. The types.Position Source reads the go files content kept in the position Overlay.
+// The LoadSourceContext with the config Dir keeps the files.
+// The sources might import only the standard library and their own packages.
+func LoadSource(files map[string]string) (types.PackageMap, Diagnostics, error) {
+	return LoadSourceContext(context.Background(), LoadConfig{WithComments: true}, files)
+}
+
+// LoadTxtar parses the packages of the Go source files defined in given txtar archive i.e.:
+//
+//	-- go.mod --
+//	module example.com/foo
+//	-- foo.go --
+//	package foo
+//
+// The archive files are loaded the same way as by the LoadSource function.
+func LoadTxtar(data []byte) (types.PackageMap, Diagnostics, error) {
+	archive := txtar.Parse(data)
+	files := make(map[string]string, len(archive.Files))
+	for _, f := range archive.Files {
+		files[f.Name] = string(f.Data)
+	}
+	return LoadSource(files)
+}
+
+// LoadSourceContext parses the packages of given Go source files with given config. The packages are loaded
+// the same way as by the LoadSource function. If the config Dir is defined, the files are written into it and are
+// kept after the load, so that the positions of the result remain valid. Otherwise, the config Dir is set
+// to the temporary module directory removed after the load, whose go files are kept in the config Overlay.
+// If the config doesn't define the Paths nor PkgNames all the module packages are loaded.
+func LoadSourceContext(ctx context.Context, cfg LoadConfig, files map[string]string) (types.PackageMap, Diagnostics, error) {
+	dir, temporary := cfg.Dir, cfg.Dir == ""
+	if temporary {
+		tmp, err := os.MkdirTemp("", "gentools-source-")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	} else if abs, err := filepath.Abs(dir); err != nil {
+		return nil, nil, err
+	} else {
+		dir = abs
+	}
+
+	if _, ok := files["go.mod"]; !ok {
+		files = copySourceFiles(files)
+		files["go.mod"] = "module " + SourceModulePath + "\n\ngo 1.19\n"
+	}
+	var overlay map[string][]byte
+	if temporary {
+		// The removed go files are loaded from the overlay, so that the positions Source remains valid.
+		overlay = make(map[string][]byte, len(files)+len(cfg.Overlay))
+		for fileName, content := range cfg.Overlay {
+			if !filepath.IsAbs(fileName) {
+				fileName = filepath.Join(dir, fileName)
+			}
+			overlay[fileName] = content
+		}
+	}
+	for name, content := range files {
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if rel, ok := relativeDir(dir, fileName); !ok || rel == "" {
+			return nil, nil, &os.PathError{Op: "write", Path: name, Err: os.ErrInvalid}
+		}
+		if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0o644); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := overlay[fileName]; temporary && !ok && strings.HasSuffix(fileName, ".go") {
+			overlay[fileName] = []byte(content)
+		}
+	}
+	if temporary {
+		cfg.Overlay = overlay
+	}
+
+	cfg.Dir = dir
+	// The source module is not a part of any workspace.
+	cfg.Env = append(cfg.environ(), "GOWORK=off", "GO111MODULE=on")
+	if len(cfg.Paths) == 0 && len(cfg.PkgNames) == 0 {
+		cfg.Paths = []string{"./..."}
+	}
+	return LoadPackagesContext(ctx, cfg)
+}
+
+func copySourceFiles(files map[string]string) map[string]string {
+	result := make(map[string]string, len(files)+1)
+	for name, content := range files {
+		result[name] = content
+	}
+	return result
+}
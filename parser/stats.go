@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"time"
+)
+
+// LoadStats are the statistics of the packages load.
+type LoadStats struct {
+	// Phases are the total durations of the load phases.
+	Phases map[Phase]time.Duration
+	// Packages is the number of the parsed packages, including the ones restored from the cache.
+	Packages int
+	// Cached is the number of the packages restored from the cache.
+	Cached int
+	// Types is the number of the named types and functions of the parsed packages.
+	Types int
+	// Declarations is the number of the variables and constants of the parsed packages.
+	Declarations int
+	// PeakWorkers is the peak number of the packages parsed concurrently.
+	PeakWorkers int
+	// Matched are the sorted import paths of the packages matched by the LoadConfig.Paths and PkgNames.
+	Matched []string
+}
+
+// resetStats resets the statistics of the load. The matched packages are preserved.
+func (p *packageMap) resetStats() {
+	p.stats, p.parsed = LoadStats{Matched: p.matched}, map[string]struct{}{}
+}
+
+// runPhase runs given load phase, reports its progress and adds its duration to the load statistics.
+func (p *packageMap) runPhase(cfg *LoadConfig, phase Phase, fn func()) time.Duration {
+	now := time.Now()
+	cfg.progress(Progress{Kind: PhaseStarted, Phase: phase})
+	fn()
+	elapsed := time.Since(now)
+	cfg.progress(Progress{Kind: PhaseFinished, Phase: phase, Elapsed: elapsed})
+	if p.stats.Phases == nil {
+		p.stats.Phases = map[Phase]time.Duration{}
+	}
+	p.stats.Phases[phase] += elapsed
+	return elapsed
+}
+
+// finishStats counts the types and declarations of the parsed packages, and stores the statistics in the config.
+func (p *packageMap) finishStats(cfg *LoadConfig) {
+	if cfg.Stats == nil {
+		return
+	}
+	p.stats.Packages, p.stats.Types, p.stats.Declarations = len(p.parsed), 0, 0
+	for path := range p.parsed {
+		pkg, ok := p.read(path)
+		if !ok {
+			continue
+		}
+		pkg.Lock()
+		p.stats.Types += len(pkg.Types)
+		p.stats.Declarations += len(pkg.Declarations)
+		pkg.Unlock()
+	}
+	*cfg.Stats = p.stats
+}
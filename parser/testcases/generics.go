@@ -0,0 +1,88 @@
+package testcases
+
+// List is the generic linked list.
+type List[T any] struct {
+	next  *List[T]
+	Value T
+}
+
+// Push adds the value to the list.
+func (l *List[T]) Push(v T) *List[T] {
+	return &List[T]{next: l, Value: v}
+}
+
+// Set is the generic set of comparable keys.
+type Set[K comparable] map[K]struct{}
+
+// Has checks if the set contains given key.
+func (s Set[K]) Has(k K) bool {
+	_, ok := s[k]
+	return ok
+}
+
+// Pair is the generic key, value pair.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Getter is the generic interface.
+type Getter[T any] interface {
+	Get() T
+}
+
+// Number is the constraint interface of numbers.
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Map maps the input slice into the slice of another type.
+func Map[T, U any](in []T, fn func(T) U) []U {
+	out := make([]U, len(in))
+	for i, v := range in {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// Sum gets the sum of the numbers.
+func Sum[N Number](in ...N) N {
+	var sum N
+	for _, v := range in {
+		sum += v
+	}
+	return sum
+}
+
+// Generics is the structure that uses instantiated generic types.
+type Generics struct {
+	Ints    List[int]
+	Keys    Set[string]
+	Pair    *Pair[string, Foo]
+	Getter  Getter[FooID]
+	Indexed map[FooID]Pair[int, []byte]
+}
+
+// Integer is the constraint interface of signed integers.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Numeric is the constraint interface that embeds another constraint.
+type Numeric interface {
+	Integer | ~float32 | ~float64
+}
+
+// Key is the constraint interface of comparable stringers.
+type Key interface {
+	comparable
+	String() string
+}
+
+// Max gets the maximum of the inputs.
+func Max[T ~int | ~string](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -71,8 +71,9 @@ type (
 )
 
 type NotEmpty interface {
-	Call(ctx context.Context, options ...string) (n int, err error)
 	InheritMe
+	// Call executes the call with given options.
+	Call(ctx context.Context, options ...string) (n int, err error)
 }
 
 // InheritMe is an interface that will be inherited.
@@ -0,0 +1,42 @@
+// Package broken contains declarations with compile errors used to test the best effort loading.
+package broken
+
+// Valid is the struct that should be parsed regardless of the errors in the package.
+type Valid struct {
+	ID   int
+	Name string
+}
+
+// Invalid is the struct with the field of undefined type.
+type Invalid struct {
+	ID      int
+	Unknown Undefined
+}
+
+// Counter is the valid constant.
+const Counter int = 10
+
+// Wrapper is the type wrapper over undefined type.
+type Wrapper Undefined
+
+// Process is the function with undefined parameter type.
+func Process(in []Undefined, n int) error {
+	return nil
+}
+
+// Box is the generic struct with the field of undefined type.
+type Box[T any] struct {
+	Value   T
+	Missing Undefined
+}
+
+// IntBox and StringBox are the instances of the generic struct with the unresolved field.
+var (
+	IntBox    Box[int]
+	StringBox Box[string]
+)
+
+// Mapped is the struct with the map of distinct undefined key and value types.
+type Mapped struct {
+	M map[UndefinedKey]UndefinedValue
+}
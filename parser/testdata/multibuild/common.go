@@ -0,0 +1,15 @@
+// Package multibuild contains declarations that differ between the build contexts.
+package multibuild
+
+import "github.com/kucjac/gentools/parser/testdata/multibuild/shared"
+
+// Common is the type declared under all build contexts.
+type Common struct {
+	ID     int
+	Shared shared.Shared
+}
+
+// Name gets the common name.
+func (c Common) Name() string {
+	return "common"
+}
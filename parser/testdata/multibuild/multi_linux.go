@@ -0,0 +1,12 @@
+package multibuild
+
+// LinuxOnly is the type declared only on linux.
+type LinuxOnly struct {
+	Common Common
+}
+
+// Linux is the method declared only on linux.
+func (c Common) Linux() {}
+
+// Handle is the type declared on both linux and windows.
+type Handle uintptr
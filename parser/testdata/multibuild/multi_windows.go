@@ -0,0 +1,15 @@
+package multibuild
+
+// WindowsOnly is the type declared only on windows.
+type WindowsOnly struct {
+	Common *Common
+}
+
+// WindowsVersion is the constant declared only on windows.
+const WindowsVersion string = "10"
+
+// Windows is the method declared only on windows.
+func (c Common) Windows() {}
+
+// Handle is the type declared on both linux and windows.
+type Handle uintptr
@@ -0,0 +1,7 @@
+// Package shared contains the declarations with the same files under all build contexts.
+package shared
+
+// Shared is the type declared in the dependency of the multibuild package.
+type Shared struct {
+	Name string
+}
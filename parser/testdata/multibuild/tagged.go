@@ -0,0 +1,6 @@
+//go:build custom
+
+package multibuild
+
+// Tagged is the function declared only with the 'custom' tag.
+func Tagged() {}
@@ -0,0 +1,10 @@
+package app
+
+import (
+	"github.com/kucjac/gentools/parser/testdata/reload/base"
+)
+
+// Service is the type depending on the reloaded package.
+type Service struct {
+	Model *base.Model
+}
@@ -0,0 +1,17 @@
+package base
+
+import (
+	"time"
+)
+
+// Model is the model reloaded by the tests.
+type Model struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+// Removed is the type removed by the tests.
+type Removed int
+
+// Unchanged is the type not changed by the tests.
+type Unchanged string
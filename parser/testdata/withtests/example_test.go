@@ -0,0 +1,21 @@
+package withtests_test
+
+import (
+	"fmt"
+
+	"github.com/kucjac/gentools/parser/testdata/withtests"
+)
+
+// ExampleGreet shows how to greet.
+func ExampleGreet() {
+	fmt.Println(withtests.Greet())
+	// Output: hello
+}
+
+func Example_unordered() {
+	fmt.Println("a")
+	fmt.Println("b")
+	// Unordered output:
+	// b
+	// a
+}
@@ -0,0 +1,7 @@
+// Package withtests contains the package with its test files.
+package withtests
+
+// Greet gets the greeting.
+func Greet() string {
+	return "hello"
+}
@@ -0,0 +1,20 @@
+package withtests
+
+import (
+	"testing"
+)
+
+// fixture is the test helper type declared in the test file.
+type fixture struct {
+	name string
+}
+
+func newFixture() *fixture {
+	return &fixture{name: Greet()}
+}
+
+func TestGreet(t *testing.T) {
+	if newFixture().name != "hello" {
+		t.Fail()
+	}
+}
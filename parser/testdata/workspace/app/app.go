@@ -0,0 +1,12 @@
+package app
+
+import (
+	"example.com/dep"
+	"example.com/shared/util"
+)
+
+// App uses the vendored and the replaced packages.
+type App struct {
+	Dep  dep.Dep
+	Util util.Util
+}
@@ -0,0 +1,13 @@
+module example.com/app
+
+go 1.19
+
+require (
+	example.com/dep v1.2.0
+	example.com/shared v0.0.0
+)
+
+replace (
+	// The shared module is developed along with the app.
+	example.com/shared => "../shared" // The quoted local directory.
+)
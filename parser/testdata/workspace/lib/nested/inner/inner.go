@@ -0,0 +1,4 @@
+package inner
+
+// Inner is the nested module type.
+type Inner struct{}
@@ -0,0 +1,4 @@
+package sub
+
+// Sub is the workspace module type.
+type Sub struct{}
@@ -0,0 +1,4 @@
+package util
+
+// Util is the locally replaced module type.
+type Util struct{}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"go/ast"
+	"go/doc"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// isTestVariant checks if given package is the variant of the package compiled for the tests i.e.: 'foo [foo.test]'.
+func isTestVariant(pkg *packages.Package) bool {
+	return strings.Contains(pkg.ID, " [")
+}
+
+// isTestMain checks if given package is the generated test main package i.e.: 'foo.test'.
+func isTestMain(pkg *packages.Package) bool {
+	return pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test")
+}
+
+// testedPackage gets the path of the package under test, if given package is its test variant or its external
+// test package. Otherwise, it returns an empty string.
+func testedPackage(pkg *packages.Package) string {
+	i := strings.Index(pkg.ID, " [")
+	if i == -1 || !strings.HasSuffix(pkg.ID, ".test]") {
+		return ""
+	}
+	forTest := strings.TrimSuffix(pkg.ID[i+2:len(pkg.ID)-1], ".test")
+	if pkg.PkgPath != forTest && pkg.PkgPath != forTest+"_test" {
+		// This is a dependency compiled for the tests of another package.
+		return ""
+	}
+	return forTest
+}
+
+// parseExamples parses the testable example functions declared in the package test files.
+func (r *rootPackage) parseExamples(p *types.Package) {
+	var testFiles []*ast.File
+	for _, file := range r.pkgPkg.Syntax {
+		if strings.HasSuffix(r.pkgPkg.Fset.Position(file.Pos()).Filename, "_test.go") {
+			testFiles = append(testFiles, file)
+		}
+	}
+	for _, ex := range doc.Examples(testFiles...) {
+		example := &types.Example{
+			Name:        ex.Name,
+			Comment:     ex.Doc,
+			Output:      ex.Output,
+			Unordered:   ex.Unordered,
+			EmptyOutput: ex.EmptyOutput,
+		}
+		if fn, ok := p.GetFunction("Example" + ex.Name); ok {
+			example.Func = fn
+			example.Pos = fn.Pos
+		}
+		p.Examples = append(p.Examples, example)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !go1.22
+
+package parser
+
+import (
+	gotypes "go/types"
+)
+
+// unalias returns the type that the alias refers to. Prior to the go1.22 the go/types package doesn't represent
+// the aliases as a separate type, thus the input type is returned as it is.
+func unalias(tp gotypes.Type) gotypes.Type {
+	return tp
+}
@@ -0,0 +1,13 @@
+//go:build go1.22
+
+package parser
+
+import (
+	gotypes "go/types"
+)
+
+// unalias returns the type that the alias refers to. Since the go1.22 the go/types package might represent
+// the aliases i.e. 'any' as a separate *types.Alias type.
+func unalias(tp gotypes.Type) gotypes.Type {
+	return gotypes.Unalias(tp)
+}
@@ -0,0 +1,331 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kucjac/gentools/types"
+)
+
+// DefaultWatchInterval is the default interval of checking the watched directories for the file changes.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// Event is the semantic change event of the named type, function or their member, found by the Watcher.
+type Event struct {
+	PkgPath string
+	// TypeName is the name of the changed named type or function.
+	TypeName string
+	// Type is the changed type. For the removed types it is the type before the change.
+	Type types.Type
+	// Member is the name of the changed field or method. It is empty if the change relates to the whole type.
+	Member string
+	// Method states if the changed Member is a method.
+	Method bool
+	Kind   ChangeKind
+}
+
+// String implements fmt.Stringer interface. It describes the change i.e.: 'struct Foo gained field Bar'.
+func (e Event) String() string {
+	typeName := typeKindName(e.Type) + " " + e.TypeName
+	switch {
+	case e.Member == "":
+		return typeName + " " + e.Kind.String()
+	case e.Method:
+		switch e.Kind {
+		case ChangeAdded:
+			return "method " + e.Member + " added to " + typeName
+		case ChangeRemoved:
+			return "method " + e.Member + " removed from " + typeName
+		default:
+			return "method " + e.Member + " of " + typeName + " changed"
+		}
+	default:
+		switch e.Kind {
+		case ChangeAdded:
+			return typeName + " gained field " + e.Member
+		case ChangeRemoved:
+			return typeName + " lost field " + e.Member
+		default:
+			return "field " + e.Member + " of " + typeName + " changed"
+		}
+	}
+}
+
+func typeKindName(t types.Type) string {
+	switch t.(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Function:
+		return "func"
+	default:
+		return "type"
+	}
+}
+
+// Events gets the semantic events of the changes. The modified type results in an event for each of its
+// changed members, or in a single event if none of its members were changed i.e. only its comment was changed.
+func (c Changes) Events() []Event {
+	var events []Event
+	for _, change := range c {
+		event := Event{PkgPath: change.PkgPath, TypeName: change.Name, Type: change.Type, Kind: change.Kind}
+		if len(change.Members) == 0 {
+			events = append(events, event)
+			continue
+		}
+		for _, member := range change.Members {
+			event.Member, event.Method, event.Kind = member.Name, member.Method, member.Kind
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Watcher watches the directories of the LoadConfig.Paths and reloads their packages on the file changes.
+// The changes of the reloaded types are delivered as the semantic Events. Both the Events and Errors channels
+// needs to be received from, as the watcher waits until each event and error is received.
+// The watched package map is modified while the Watcher is locked, thus it needs to be locked while reading
+// the map concurrently.
+type Watcher struct {
+	sync.Mutex
+	// Events are the semantic change events of the reloaded packages.
+	Events <-chan Event
+	// Errors are the errors of reading the watched directories and reloading their packages. The failed reload
+	// is retried on each check until it succeeds.
+	Errors <-chan error
+
+	pkgs     types.PackageMap
+	cfg      LoadConfig
+	interval time.Duration
+	dirs     map[string]map[string]fileState
+	events   chan Event
+	errors   chan error
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// fileState is the state of the watched file used to detect its changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch loads the packages of the LoadConfig.Paths into given map if not loaded yet, and starts watching their
+// directories. The path patterns i.e.: './...' are expanded into the directories of the packages matched
+// at the start of the watch. The directories are checked for the file changes every interval, or the DefaultWatchInterval
+// if the interval is not positive. The watcher is stopped when given context is done or the watcher is closed.
+func Watch(ctx context.Context, pkgs types.PackageMap, cfg LoadConfig, interval time.Duration) (*Watcher, error) {
+	if len(cfg.Paths) == 0 {
+		return nil, errors.New("no paths to watch")
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	watched, err := watchedDirs(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]map[string]fileState, len(watched))
+	for _, dir := range watched {
+		// The state is read before the packages are loaded, so that the changes made meanwhile are not missed.
+		if dirs[dir], err = readDirState(dir); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := UpdatePackagesContext(ctx, pkgs, cfg); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events, errs := make(chan Event), make(chan error)
+	w := &Watcher{
+		Events:   events,
+		Errors:   errs,
+		pkgs:     pkgs,
+		cfg:      cfg,
+		interval: interval,
+		dirs:     dirs,
+		events:   events,
+		errors:   errs,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+// Close stops the watcher, and waits until it is finished. The Events and Errors channels are closed.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) run(ctx context.Context) {
+	defer func() {
+		close(w.events)
+		close(w.errors)
+		close(w.done)
+	}()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := w.changedDirs()
+		if err != nil && !w.sendError(ctx, err) {
+			return
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		dirs := make([]string, 0, len(changed))
+		for dir := range changed {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		events, err := w.reload(ctx, dirs)
+		if err != nil {
+			// The directory states are not updated, so that the reload is retried on the next check.
+			if ctx.Err() != nil || !w.sendError(ctx, err) {
+				return
+			}
+			continue
+		}
+		for dir, state := range changed {
+			w.dirs[dir] = state
+		}
+		for _, event := range events {
+			select {
+			case w.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// changedDirs gets the current states of the watched directories with changed, added or removed go files.
+// The states are not stored by the watcher until the directories are successfully reloaded.
+func (w *Watcher) changedDirs() (map[string]map[string]fileState, error) {
+	var errs []string
+	changed := map[string]map[string]fileState{}
+	for dir, prev := range w.dirs {
+		state, err := readDirState(dir)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !equalDirState(prev, state) {
+			changed[dir] = state
+		}
+	}
+	if len(errs) != 0 {
+		return changed, fmt.Errorf("reading watched directories failed: %s", strings.Join(errs, "; "))
+	}
+	return changed, nil
+}
+
+// reload reloads the packages of given directories, and gets the events of their changes.
+func (w *Watcher) reload(ctx context.Context, dirs []string) ([]Event, error) {
+	cfg := w.cfg
+	cfg.Paths, cfg.PkgNames = dirs, nil
+	w.Lock()
+	changes, diagnostics, err := ReloadPackagesContext(ctx, w.pkgs, cfg)
+	w.Unlock()
+	if err != nil {
+		if errs := diagnostics.Errors(); len(errs) != 0 {
+			return nil, fmt.Errorf("%w:\n%s", err, errs)
+		}
+		return nil, err
+	}
+	return changes.Events(), nil
+}
+
+func (w *Watcher) sendError(ctx context.Context, err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchedDirs gets the absolute directories of the config Paths. The path patterns are expanded into the directories
+// of the matched packages.
+func watchedDirs(ctx context.Context, cfg *LoadConfig) ([]string, error) {
+	var dirs, patterns []string
+	for _, path := range cfg.Paths {
+		if isPackagePattern(path) {
+			pattern, err := absPathPattern(cfg, path)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, pattern)
+			continue
+		}
+		dir, err := cfg.absPath(path)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(patterns) == 0 {
+		return dirs, nil
+	}
+	matched, err := matchPackages(ctx, cfg, packages.NeedName|packages.NeedFiles, patterns)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range matched {
+		if len(pkg.GoFiles) != 0 {
+			dirs = append(dirs, filepath.Dir(pkg.GoFiles[0]))
+		}
+	}
+	return dirs, nil
+}
+
+func readDirState(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	state := map[string]fileState{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		state[entry.Name()] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return state, nil
+}
+
+func equalDirState(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, fa := range a {
+		fb, ok := b[name]
+		if !ok || fb.size != fa.size || !fb.modTime.Equal(fa.modTime) {
+			return false
+		}
+	}
+	return true
+}
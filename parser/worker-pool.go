@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// workerPool runs the package parsing jobs with a bounded number of workers.
+type workerPool struct {
+	size int
+	// peak is the peak number of the jobs in flight.
+	peak int
+}
+
+func newWorkerPool(concurrency int) *workerPool {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return &workerPool{size: concurrency}
+}
+
+// run runs the job for each root package from the list, which is sorted by the import number. A job is started
+// after the jobs of all the package imports from the list are finished, so that the dependencies are handled first.
+// The packages whose imports could not be finished first (i.e. the test variants importing the packages that
+// depends on the package under test) are started in the list order once no other job is ready nor running.
+func (w *workerPool) run(list []*rootPackage, job func(r *rootPackage)) {
+	if len(list) == 0 {
+		return
+	}
+	index := make(map[*rootPackage]int, len(list))
+	byPath := make(map[string]*rootPackage, len(list))
+	for i, r := range list {
+		index[r] = i
+		byPath[r.pkgPkg.PkgPath] = r
+	}
+	pending := make(map[*rootPackage]int, len(list))
+	dependents := map[*rootPackage][]*rootPackage{}
+	for _, r := range list {
+		for path := range r.pkgPkg.Imports {
+			if dep, ok := byPath[path]; ok && dep != r {
+				pending[r]++
+				dependents[dep] = append(dependents[dep], r)
+			}
+		}
+	}
+
+	var ready []*rootPackage
+	for _, r := range list {
+		if pending[r] == 0 {
+			ready = append(ready, r)
+		}
+	}
+
+	workers := w.size
+	if workers > len(list) {
+		workers = len(list)
+	}
+	jobs, done := make(chan *rootPackage), make(chan *rootPackage)
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				job(r)
+				done <- r
+			}
+		}()
+	}
+
+	started := make(map[*rootPackage]bool, len(list))
+	var running, next int
+	for finished := 0; finished < len(list); {
+		if len(ready) == 0 && running == 0 {
+			// None of the remaining packages have their imports finished, start the first one of them.
+			for started[list[next]] {
+				next++
+			}
+			ready = append(ready, list[next])
+		}
+		var (
+			send chan *rootPackage
+			r    *rootPackage
+		)
+		if len(ready) != 0 && running < workers {
+			send, r = jobs, ready[0]
+		}
+		select {
+		case send <- r:
+			ready = ready[1:]
+			started[r] = true
+			if running++; running > w.peak {
+				w.peak = running
+			}
+		case r := <-done:
+			running--
+			finished++
+			for _, dependent := range dependents[r] {
+				if pending[dependent]--; pending[dependent] == 0 && !started[dependent] {
+					ready = append(ready, dependent)
+				}
+			}
+			sort.Slice(ready, func(i, j int) bool { return index[ready[i]] < index[ready[j]] })
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -13,27 +13,46 @@ var _ Type = (*Alias)(nil)
 
 // Alias is the type that represents wrapped and named another type.
 // I.e.: 'type Custom int' would be an Alias over BuiltIn(int) type.
+// The Alias also represents the Go type alias declaration i.e.: 'type Reader = io.Reader', in which case
+// the TypeAlias is true and the alias is identical to its Type.
 type Alias struct {
-	Comment   string
-	Pkg       *Package
-	AliasName string
-	Type      Type
-	Methods   []Function
+	Comment    string
+	Pkg        *Package
+	AliasName  string
+	Type       Type
+	Methods    []Function
+	TypeParams TypeParams
+	// TypeArgs are the type arguments of the instantiated generic alias i.e.: 'Set[string]'.
+	TypeArgs []Type
+	// Origin is the generic alias of given instantiated alias.
+	Origin *Alias
+	// Pos is the source position of the type declaration.
+	Pos Position
+	// Builds are the build configurations under which the type is declared.
+	Builds BuildContexts
+	// Markers are the structured annotations of the type.
+	Markers Markers
+	// TypeAlias states if the type is the Go type alias i.e.: 'type Reader = io.Reader', and not the defined type
+	// i.e.: 'type ID int64'. The type alias is identical to its Type, and has no methods of its own.
+	TypeAlias bool
+	// BuildConstraint is the build constraint of the files declaring the type under the loaded build contexts.
+	BuildConstraint string
 }
 
 // Name implements Type interface.
 func (a *Alias) Name(identified bool, packageContext string) string {
+	name := a.AliasName + typeArgsName(a.TypeParams, a.TypeArgs, identified, packageContext)
 	if identified && packageContext != a.Pkg.Path {
 		if i := a.Pkg.Identifier; i != "" {
-			return i + "." + a.AliasName
+			return i + "." + name
 		}
 	}
-	return a.AliasName
+	return name
 }
 
 // FullName implements Type interface.
 func (a *Alias) FullName() string {
-	return a.Pkg.Path + "/" + a.AliasName
+	return a.Pkg.Path + "/" + a.AliasName + typeArgsFullName(a.TypeParams, a.TypeArgs)
 }
 
 // Package implements Type interface.
@@ -63,18 +82,32 @@ func (a *Alias) Zero(identified bool, packageContext string) string {
 	return a.Name(identified, packageContext) + "(" + t.Zero(identified, packageContext) + ")"
 }
 
-// Equal implements Type interface.
+// Equal implements Type interface. The type alias is equal to the type it refers to.
 func (a *Alias) Equal(another Type) bool {
-	wt, ok := another.(*Alias)
+	if a.TypeAlias && a.Type != nil {
+		return a.Type.Equal(another)
+	}
+	wt, ok := Unalias(another).(*Alias)
 	if !ok {
-		return false
+		return isOpaqueOf(another, a)
 	}
-	return a.Pkg == wt.Pkg && wt.AliasName == a.AliasName
+	return a.Pkg == wt.Pkg && wt.AliasName == a.AliasName && typeArgsEqual(a.TypeArgs, wt.TypeArgs)
+}
+
+// IsGeneric checks if given alias is a generic type that is not instantiated.
+func (a *Alias) IsGeneric() bool {
+	return len(a.TypeParams) != 0 && len(a.TypeArgs) == 0
 }
 
 // Implements checks if the alias types implements provided interface.
 // The argument isPointer states if given the pointer to alias or an alias by itself implements given interface.
 func (a *Alias) Implements(interfaceType *Interface, isPointer bool) bool {
+	if a.TypeAlias && a.Type != nil {
+		if isPointer {
+			return Implements(PointerTo(a.Type), interfaceType)
+		}
+		return Implements(a.Type, interfaceType)
+	}
 	return implements(interfaceType, a, isPointer)
 }
 
@@ -82,6 +115,18 @@ func (a *Alias) getMethods() []Function {
 	return a.Methods
 }
 
+// Unalias gets the type referred by given type alias i.e.: 'io.Reader' for the 'type Reader = io.Reader'.
+// The chains of the aliases are followed. The types other than the type aliases are returned as they are.
+func Unalias(t Type) Type {
+	for {
+		a, ok := t.(*Alias)
+		if !ok || !a.TypeAlias || a.Type == nil {
+			return t
+		}
+		t = a.Type
+	}
+}
+
 func aliasOf(pkg *Package, name string, aType Type) *Alias {
 	a := &Alias{
 		Pkg:       pkg,
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"strings"
+)
+
+// BuildContext is the build configuration under which the packages are loaded i.e.: 'linux/amd64' with the 'integration' tag.
+type BuildContext struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// String implements fmt.Stringer interface. It returns the context in a form of 'linux/amd64' or 'linux/amd64,tag1,tag2'.
+func (b BuildContext) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(b.GOOS)
+	if b.GOARCH != "" {
+		sb.WriteRune('/')
+		sb.WriteString(b.GOARCH)
+	}
+	for _, tag := range b.Tags {
+		sb.WriteRune(',')
+		sb.WriteString(tag)
+	}
+	return sb.String()
+}
+
+// Equal checks if the build contexts are equal.
+func (b BuildContext) Equal(another BuildContext) bool {
+	if b.GOOS != another.GOOS || b.GOARCH != another.GOARCH || len(b.Tags) != len(another.Tags) {
+		return false
+	}
+	for i := range b.Tags {
+		if b.Tags[i] != another.Tags[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildContexts is the list of build configurations, under which given type or declaration exists.
+// The list is empty if the packages were not loaded with multiple build configurations.
+type BuildContexts []BuildContext
+
+// Contains checks if the list contains given build context.
+func (b BuildContexts) Contains(bc BuildContext) bool {
+	for _, c := range b {
+		if c.Equal(bc) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has checks if the list contains a build context with given GOOS.
+func (b BuildContexts) Has(goos string) bool {
+	for _, c := range b {
+		if c.GOOS == goos {
+			return true
+		}
+	}
+	return false
+}
@@ -34,6 +34,8 @@ var (
 	Complex128    Type
 	String        Type
 	UnsafePointer Type
+	Any           Type
+	Comparable    Type
 )
 
 func init() {
@@ -105,10 +107,22 @@ func init() {
 			Out:      []FuncParam{{Type: String}},
 		}},
 	}
+	er.ExplicitMethods = er.Methods
 
 	builtIn.Interfaces = append(builtIn.Interfaces, er)
 	builtIn.Types["error"] = er
 	Error = er
+
+	// The 'any' and 'comparable' are the predeclared constraint interfaces used by the generic types.
+	an := &Interface{Pkg: builtIn, InterfaceName: "any"}
+	builtIn.Interfaces = append(builtIn.Interfaces, an)
+	builtIn.Types["any"] = an
+	Any = an
+
+	cp := &Interface{Pkg: builtIn, InterfaceName: "comparable"}
+	builtIn.Interfaces = append(builtIn.Interfaces, cp)
+	builtIn.Types["comparable"] = cp
+	Comparable = cp
 }
 
 // IsBuiltIn checks if given name is a built in type.
@@ -233,7 +247,7 @@ func (b BuiltInType) String() string {
 
 // Equal checks if given built in type is equal to another Type.
 func (b *BuiltInType) Equal(another Type) bool {
-	bt, ok := another.(*BuiltInType)
+	bt, ok := Unalias(another).(*BuiltInType)
 	if !ok {
 		return false
 	}
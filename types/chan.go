@@ -69,7 +69,7 @@ func (c *Chan) Zero(_ bool, _ string) string {
 
 // Equal implements Type interface.
 func (c *Chan) Equal(another Type) bool {
-	ct, ok := another.(*Chan)
+	ct, ok := Unalias(another).(*Chan)
 	if !ok {
 		return false
 	}
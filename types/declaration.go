@@ -14,6 +14,16 @@ type Declaration struct {
 	Constant bool
 	Val      constant.Value
 	Package  *Package
+	// Pos is the source position of the declaration.
+	Pos Position
+	// Builds are the build configurations under which the declaration exists.
+	Builds BuildContexts
+	// LineComment is the trailing comment of the declaration i.e.: 'const A = 1 // The first.'.
+	LineComment string
+	// Markers are the structured annotations of the declaration.
+	Markers Markers
+	// BuildConstraint is the build constraint of the files declaring the declaration under the loaded build contexts.
+	BuildConstraint string
 }
 
 // ConstValue gets the basic value of given constant declaration type.
@@ -0,0 +1,17 @@
+package types
+
+// Example is the testable example function declared in the package test files i.e.: 'func ExampleFoo_bar()'.
+type Example struct {
+	// Name is the name of the exemplified item with an optional suffix i.e.: 'Foo_bar'.
+	Name    string
+	Comment string
+	// Output is the expected output of the example, defined in the '// Output:' comment.
+	Output string
+	// Unordered states if the output is defined in the '// Unordered output:' comment.
+	Unordered bool
+	// EmptyOutput states if the example expects an empty output.
+	EmptyOutput bool
+	// Func is the example function.
+	Func *Function
+	Pos  Position
+}
@@ -0,0 +1,38 @@
+package types
+
+// File is the Go source file of the package.
+type File struct {
+	// Path is the absolute path of the file.
+	Path string
+	// BuildConstraint is the build constraint expression of the file i.e.: 'linux && amd64'. The legacy
+	// '// +build' lines are converted into the '//go:build' expression form.
+	BuildConstraint string
+	// Generated states if the file contains the 'Code generated ... DO NOT EDIT.' comment.
+	Generated bool
+	// Directives are the compiler directives of the file in the source order.
+	Directives []Directive
+}
+
+// FindDirectives gets the file directives with given name i.e.: 'go:generate'.
+func (f *File) FindDirectives(name string) []Directive {
+	var result []Directive
+	for _, d := range f.Directives {
+		if d.Name == name {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// Directive is the compiler directive comment i.e.: '//go:generate stringer -type=Kind', '//go:embed static/*',
+// '//go:build linux' or '//go:linkname localName importPath.name'.
+type Directive struct {
+	// Name is the directive name i.e.: 'go:generate'.
+	Name string
+	// Args are the directive arguments. The quoted arguments i.e.: '"a b"' are unquoted.
+	Args []string
+	// Text is the raw text of the directive arguments.
+	Text string
+	// Pos is the source position of the directive comment.
+	Pos Position
+}
@@ -8,13 +8,25 @@ var _ Type = (*Function)(nil)
 
 // Function is the function type used for getting.
 type Function struct {
-	Comment  string
-	Pkg      *Package
-	Receiver *Receiver
-	FuncName string
-	In       []FuncParam
-	Out      []FuncParam
-	Variadic bool
+	Comment    string
+	Pkg        *Package
+	Receiver   *Receiver
+	FuncName   string
+	In         []FuncParam
+	Out        []FuncParam
+	Variadic   bool
+	TypeParams TypeParams
+	// Pos is the source position of the function or method declaration.
+	Pos Position
+	// Builds are the build configurations under which the function or method is declared.
+	Builds BuildContexts
+	// LineComment is the trailing comment of the interface method.
+	LineComment string
+	// Markers are the structured annotations of the function or method.
+	Markers Markers
+	// BuildConstraint is the build constraint of the files declaring the function or method under the loaded
+	// build contexts.
+	BuildConstraint string
 }
 
 // Name implements Type interface.
@@ -56,6 +68,7 @@ func (f Function) String() string {
 		sb.WriteRune(' ')
 	}
 	sb.WriteString(f.FuncName)
+	sb.WriteString(f.TypeParams.Declaration(true, ""))
 	sb.WriteRune('(')
 	for i := range f.In {
 		sb.WriteString(f.In[i].String())
@@ -80,7 +93,7 @@ func (f *Function) Zero(_ bool, _ string) string {
 
 // Equal implements Type interface.
 func (f *Function) Equal(another Type) bool {
-	ft, ok := another.(*Function)
+	ft, ok := Unalias(another).(*Function)
 	if !ok {
 		return false
 	}
@@ -90,6 +103,11 @@ func (f *Function) Equal(another Type) bool {
 	return f.Pkg == ft.Pkg && f.FuncName == ft.FuncName
 }
 
+// IsGeneric checks if given function has type parameters.
+func (f *Function) IsGeneric() bool {
+	return len(f.TypeParams) != 0
+}
+
 // FuncParam is the input/output parameter of functions and methods.
 type FuncParam struct {
 	Name string
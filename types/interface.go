@@ -1,5 +1,9 @@
 package types
 
+import (
+	"strings"
+)
+
 var _ Type = (*Interface)(nil)
 
 // Interface is the interface type model definition.
@@ -7,22 +11,106 @@ type Interface struct {
 	Pkg           *Package
 	Comment       string
 	InterfaceName string
-	Methods       []Function
+	// Methods is the full method set of the interface, including the methods of the embedded interfaces.
+	Methods []Function
+	// ExplicitMethods are the methods declared directly in the interface.
+	ExplicitMethods []Function
+	// Embedded are the interfaces explicitly embedded in the interface i.e.: io.Reader and io.Closer in io.ReadCloser.
+	Embedded   []Type
+	TypeParams TypeParams
+	// TypeArgs are the type arguments of the instantiated generic interface i.e.: 'Iterator[int]'.
+	TypeArgs []Type
+	// Origin is the generic interface of given instantiated interface.
+	Origin *Interface
+	// Unions are the type term unions embedded in the constraint interface i.e.: 'interface{ ~int | ~int64 }'.
+	// The type set of the interface is an intersection of all its unions.
+	Unions []Union
+	// Implicit states if the interface is an implicit constraint interface i.e.: '[T ~int | ~string]'.
+	Implicit bool
+	// Pos is the source position of the interface declaration.
+	Pos Position
+	// Builds are the build configurations under which the interface is declared.
+	Builds BuildContexts
+	// Markers are the structured annotations of the interface type.
+	Markers Markers
+	// BuildConstraint is the build constraint of the files declaring the interface under the loaded build contexts.
+	BuildConstraint string
 }
 
 // Name implements Type interface.
 func (i Interface) Name(identified bool, packageContext string) string {
+	if i.InterfaceName == "" {
+		return i.literal(func(t Type) string { return t.Name(identified, packageContext) }, func(u Union) string {
+			return u.Name(identified, packageContext)
+		})
+	}
+	name := i.InterfaceName + typeArgsName(i.TypeParams, i.TypeArgs, identified, packageContext)
 	if identified && packageContext != i.Pkg.Path {
 		if identifier := i.Pkg.Identifier; identifier != "" {
-			return identifier + "." + i.InterfaceName
+			return identifier + "." + name
 		}
 	}
-	return i.InterfaceName
+	return name
 }
 
 // FullName implements Type interface.
 func (i Interface) FullName() string {
-	return i.Pkg.Path + "/" + i.InterfaceName
+	if i.InterfaceName == "" {
+		return i.literal(Type.FullName, Union.FullName)
+	}
+	return i.Pkg.Path + "/" + i.InterfaceName + typeArgsFullName(i.TypeParams, i.TypeArgs)
+}
+
+// literal gets the literal definition of the unnamed interface i.e.: 'interface{ ~int | ~string; String() string }'.
+func (i Interface) literal(typeName func(t Type) string, unionName func(u Union) string) string {
+	if i.Implicit && len(i.Unions) == 1 {
+		return unionName(i.Unions[0])
+	}
+	if len(i.Unions) == 0 && len(i.Methods) == 0 {
+		return "interface{}"
+	}
+	sb := strings.Builder{}
+	sb.WriteString("interface{ ")
+	for j, u := range i.Unions {
+		sb.WriteString(unionName(u))
+		if j != len(i.Unions)-1 || len(i.Methods) != 0 {
+			sb.WriteString("; ")
+		}
+	}
+	for j, m := range i.Methods {
+		sb.WriteString(m.FuncName)
+		sb.WriteRune('(')
+		for k, in := range m.In {
+			if m.Variadic && k == len(m.In)-1 && in.Type.Kind() == KindSlice {
+				sb.WriteString("..." + typeName(in.Type.Elem()))
+			} else {
+				sb.WriteString(typeName(in.Type))
+			}
+			if k != len(m.In)-1 {
+				sb.WriteString(", ")
+			}
+		}
+		sb.WriteRune(')')
+		if len(m.Out) > 1 {
+			sb.WriteString(" (")
+		} else if len(m.Out) == 1 {
+			sb.WriteRune(' ')
+		}
+		for k, out := range m.Out {
+			sb.WriteString(typeName(out.Type))
+			if k != len(m.Out)-1 {
+				sb.WriteString(", ")
+			}
+		}
+		if len(m.Out) > 1 {
+			sb.WriteRune(')')
+		}
+		if j != len(i.Methods)-1 {
+			sb.WriteString("; ")
+		}
+	}
+	sb.WriteString(" }")
+	return sb.String()
 }
 
 // Package implements Type interface
@@ -50,9 +138,109 @@ func (i Interface) Zero(_ bool, _ string) string {
 	return "nil"
 }
 
+// Method gets the method from the interface method set by its name.
+func (i *Interface) Method(name string) (*Function, bool) {
+	for j := range i.Methods {
+		if i.Methods[j].FuncName == name {
+			return &i.Methods[j], true
+		}
+	}
+	return nil, false
+}
+
+// IsEmbedded checks if given interface is explicitly embedded in the interface.
+func (i *Interface) IsEmbedded(another *Interface) bool {
+	for _, e := range i.Embedded {
+		if e.Equal(another) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMethodComment sets the comment of the method with given name, both in the method set and in explicit methods.
+func (i *Interface) SetMethodComment(name, comment string) {
+	if m, ok := i.Method(name); ok {
+		m.Comment = comment
+	}
+	for j := range i.ExplicitMethods {
+		if i.ExplicitMethods[j].FuncName == name {
+			i.ExplicitMethods[j].Comment = comment
+		}
+	}
+}
+
+// SetMethodLineComment sets the trailing comment of the method with given name, both in the method set
+// and in explicit methods.
+func (i *Interface) SetMethodLineComment(name, comment string) {
+	if m, ok := i.Method(name); ok {
+		m.LineComment = comment
+	}
+	for j := range i.ExplicitMethods {
+		if i.ExplicitMethods[j].FuncName == name {
+			i.ExplicitMethods[j].LineComment = comment
+		}
+	}
+}
+
+// SetMethodMarkers sets the markers of the method with given name, both in the method set and in explicit methods.
+func (i *Interface) SetMethodMarkers(name string, markers Markers) {
+	if m, ok := i.Method(name); ok {
+		m.Markers = markers
+	}
+	for j := range i.ExplicitMethods {
+		if i.ExplicitMethods[j].FuncName == name {
+			i.ExplicitMethods[j].Markers = markers
+		}
+	}
+}
+
 // IsEmpty checks if it is an empty interface -> 'interface{}'
 func (i *Interface) IsEmpty() bool {
-	return len(i.Methods) == 0
+	return len(i.Methods) == 0 && len(i.Unions) == 0 && Type(i) != Comparable
+}
+
+// IsConstraintOnly checks if the interface could be used only as a type parameter constraint.
+// These are the interfaces that contain type term unions or are the 'comparable' interface.
+func (i *Interface) IsConstraintOnly() bool {
+	return len(i.Unions) != 0 || Type(i) == Comparable
+}
+
+// TypeSetContains checks if the type set of given interface contains provided type.
+// The type needs to match at least one term of each interface union and implement all interface methods.
+func (i *Interface) TypeSetContains(tp Type) bool {
+	if Type(i) == Comparable && !IsComparable(tp) {
+		return false
+	}
+	for _, u := range i.Unions {
+		if !u.Contains(tp) {
+			return false
+		}
+	}
+	if len(i.Methods) != 0 && !Implements(tp, i) {
+		return false
+	}
+	return true
+}
+
+// isComparable checks if all types from the interface type set are comparable.
+func (i *Interface) isComparable() bool {
+	if Type(i) == Comparable {
+		return true
+	}
+	for _, u := range i.Unions {
+		comparable := true
+		for _, term := range u.Terms {
+			if !IsComparable(term.Type) {
+				comparable = false
+				break
+			}
+		}
+		if comparable {
+			return true
+		}
+	}
+	return false
 }
 
 // Implements checks if given interface implements another interface.
@@ -62,11 +250,16 @@ func (i *Interface) Implements(another *Interface) bool {
 
 // Equal implements Type interface.
 func (i *Interface) Equal(another Type) bool {
-	it, ok := another.(*Interface)
+	it, ok := Unalias(another).(*Interface)
 	if !ok {
-		return false
+		return isOpaqueOf(another, i)
 	}
-	return it.Pkg == i.Pkg && it.InterfaceName == i.InterfaceName
+	return it.Pkg == i.Pkg && it.InterfaceName == i.InterfaceName && typeArgsEqual(it.TypeArgs, i.TypeArgs)
+}
+
+// IsGeneric checks if given interface is a generic type that is not instantiated.
+func (i *Interface) IsGeneric() bool {
+	return len(i.TypeParams) != 0 && len(i.TypeArgs) == 0
 }
 
 func (i *Interface) getMethods() []Function {
@@ -116,12 +309,12 @@ func implements(interfaceToImplement *Interface, implementer methoder, pointer b
 				}
 
 				for i := 0; i < len(iMethod.In); i++ {
-					if iMethod.In[i].Type.FullName() != sMethod.In[i].Type.FullName() {
+					if !identicalTypes(iMethod.In[i].Type, sMethod.In[i].Type) {
 						return false
 					}
 				}
 				for i := 0; i < len(iMethod.Out); i++ {
-					if iMethod.Out[i].Type.FullName() != sMethod.Out[i].Type.FullName() {
+					if !identicalTypes(iMethod.Out[i].Type, sMethod.Out[i].Type) {
 						return false
 					}
 				}
@@ -148,3 +341,9 @@ func IsEmptyInterface(tp Type) bool {
 	}
 	return i.IsEmpty()
 }
+
+// identicalTypes checks if the method parameter types are identical. The type aliases are identical to the types
+// they refer to.
+func identicalTypes(a, b Type) bool {
+	return a.FullName() == b.FullName() || a.Equal(b)
+}
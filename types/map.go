@@ -49,7 +49,7 @@ func (m *Map) Zero(_ bool, _ string) string {
 
 // Equal implements Type interface.
 func (m *Map) Equal(another Type) bool {
-	mp, ok := another.(*Map)
+	mp, ok := Unalias(another).(*Map)
 	if !ok {
 		return false
 	}
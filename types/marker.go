@@ -0,0 +1,455 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MarkerKind is the kind of the marker value.
+type MarkerKind int
+
+// Enumerated marker kinds.
+const (
+	MarkerBool MarkerKind = iota + 1
+	MarkerString
+	MarkerInt
+	MarkerList
+)
+
+// String implements fmt.Stringer interface.
+func (k MarkerKind) String() string {
+	switch k {
+	case MarkerBool:
+		return "bool"
+	case MarkerString:
+		return "string"
+	case MarkerInt:
+		return "int"
+	case MarkerList:
+		return "list"
+	default:
+		return "unknown"
+	}
+}
+
+// Marker is the structured annotation of the declaration, parsed from its comment i.e.: '// +gentools:table=users'
+// or '//gentools:skip'. The marker is written in the form: 'name[=value] [arg[=value],...]', where the value
+// is either a string (optionally quoted), int, bool or a list of values i.e.: '{id,name}'.
+// The marker and the argument without the value have the boolean true value.
+type Marker struct {
+	// Name is the marker name i.e.: 'gentools:table'.
+	Name string
+	// Value is the marker value. It is either a string, int, bool or []interface{} list of these values.
+	Value interface{}
+	// Args are the marker arguments i.e.: 'unique' and 'size' in '+gentools:index=idx unique,size=10'.
+	Args map[string]interface{}
+	// Text is the marker source text without the comment prefix i.e.: 'gentools:table=users'.
+	Text string
+	// Pos is the source position of the marker comment.
+	Pos Position
+	// HasValue states if the marker value is defined in its text. The marker without the value has the true Value.
+	HasValue bool
+}
+
+// String implements fmt.Stringer interface.
+func (m Marker) String() string {
+	return "+" + m.Text
+}
+
+// Namespace gets the marker namespace, which is the first segment of its name i.e.: 'gentools'.
+func (m Marker) Namespace() string {
+	return markerNamespace(m.Name)
+}
+
+// StringValue gets the string value of the marker.
+func (m Marker) StringValue() (string, bool) {
+	v, ok := m.Value.(string)
+	return v, ok
+}
+
+// IntValue gets the int value of the marker.
+func (m Marker) IntValue() (int, bool) {
+	v, ok := m.Value.(int)
+	return v, ok
+}
+
+// BoolValue gets the bool value of the marker.
+func (m Marker) BoolValue() (bool, bool) {
+	v, ok := m.Value.(bool)
+	return v, ok
+}
+
+// ListValue gets the list value of the marker.
+func (m Marker) ListValue() ([]interface{}, bool) {
+	v, ok := m.Value.([]interface{})
+	return v, ok
+}
+
+// Arg gets the value of the marker argument with given name.
+func (m Marker) Arg(name string) (interface{}, bool) {
+	v, ok := m.Args[name]
+	return v, ok
+}
+
+// Markers is the list of the declaration markers.
+type Markers []Marker
+
+// Get gets the first marker with given name.
+func (m Markers) Get(name string) (Marker, bool) {
+	for _, marker := range m {
+		if marker.Name == name {
+			return marker, true
+		}
+	}
+	return Marker{}, false
+}
+
+// Has checks if the markers contains the marker with given name.
+func (m Markers) Has(name string) bool {
+	_, ok := m.Get(name)
+	return ok
+}
+
+// All gets all the markers with given name i.e. the markers that might be repeated.
+func (m Markers) All(name string) Markers {
+	var result Markers
+	for _, marker := range m {
+		if marker.Name == name {
+			result = append(result, marker)
+		}
+	}
+	return result
+}
+
+// MarkerText gets the marker text of the line comment. The markers are the comments starting with the '+' sign
+// i.e.: '// +gentools:table=users', or the directive like comments with the namespace i.e.: '//gentools:skip'.
+// The Go directives i.e.: '//go:generate' are not the markers. The second result is false if the comment
+// is not a marker.
+func MarkerText(comment string) (string, bool) {
+	if !strings.HasPrefix(comment, "//") {
+		return "", false
+	}
+	text := comment[2:]
+	if trimmed := strings.TrimLeftFunc(text, unicode.IsSpace); strings.HasPrefix(trimmed, "+") {
+		text = strings.TrimSpace(trimmed[1:])
+		return text, text != "" && isMarkerNameStart(rune(text[0]))
+	}
+	i := strings.IndexRune(text, ':')
+	if i <= 0 || i == len(text)-1 || text[:i] == "go" || !isMarkerName(text[:i]) || unicode.IsSpace(rune(text[i+1])) {
+		return "", false
+	}
+	return strings.TrimSpace(text), true
+}
+
+// ParseMarker parses the marker text i.e.: 'gentools:table=users'. The text might start with the '+' sign.
+func ParseMarker(text string) (Marker, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "+")
+	m := Marker{Text: text}
+	i := strings.IndexFunc(text, func(r rune) bool { return r == '=' || unicode.IsSpace(r) })
+	if i < 0 {
+		i = len(text)
+	}
+	m.Name = text[:i]
+	if !isMarkerName(m.Name) {
+		return Marker{}, fmt.Errorf("invalid marker name: '%s'", m.Name)
+	}
+
+	rest := text[i:]
+	m.Value = true
+	if strings.HasPrefix(rest, "=") {
+		tokens, err := splitMarkerTokens(rest[1:], unicode.IsSpace)
+		if err != nil {
+			return Marker{}, err
+		}
+		if len(tokens) == 0 || !strings.HasPrefix(rest[1:], tokens[0]) {
+			return Marker{}, errors.New("marker value expected")
+		}
+		if m.Value, err = parseMarkerValue(tokens[0]); err != nil {
+			return Marker{}, err
+		}
+		m.HasValue = true
+		rest = rest[1+len(tokens[0]):]
+	}
+
+	args, err := splitMarkerTokens(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	if err != nil {
+		return Marker{}, err
+	}
+	for _, arg := range args {
+		if m.Args == nil {
+			m.Args = map[string]interface{}{}
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isMarkerName(name) {
+			return Marker{}, fmt.Errorf("invalid marker argument name: '%s'", name)
+		}
+		if _, ok := m.Args[name]; ok {
+			return Marker{}, fmt.Errorf("duplicated marker argument: '%s'", name)
+		}
+		m.Args[name] = true
+		if hasValue {
+			if m.Args[name], err = parseMarkerValue(value); err != nil {
+				return Marker{}, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func parseMarkerValue(s string) (interface{}, error) {
+	switch {
+	case s == "":
+		return nil, errors.New("empty marker value")
+	case s[0] == '"' || s[0] == '`':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted marker value: %s", s)
+		}
+		return v, nil
+	case s[0] == '{':
+		if s[len(s)-1] != '}' {
+			return nil, fmt.Errorf("invalid marker list value: %s", s)
+		}
+		items, err := splitMarkerTokens(s[1:len(s)-1], func(r rune) bool { return r == ',' })
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			v, err := parseMarkerValue(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case s == "true" || s == "false":
+		return s == "true", nil
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i, nil
+	}
+	if strings.ContainsAny(s, "{}\"`") {
+		return nil, fmt.Errorf("invalid marker value: %s", s)
+	}
+	return s, nil
+}
+
+// splitMarkerTokens splits the text by the separators, that are not within the quotes nor braces.
+// The empty tokens are omitted.
+func splitMarkerTokens(s string, isSep func(r rune) bool) ([]string, error) {
+	var (
+		tokens []string
+		quote  rune
+		depth  int
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("unbalanced braces in marker: %s", s)
+			}
+		case depth == 0 && isSep(c):
+			if start < i {
+				tokens = append(tokens, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in marker: %s", s)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces in marker: %s", s)
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens, nil
+}
+
+func isMarkerNameStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// isMarkerName checks if given name is a valid marker or argument name i.e.: 'gentools:table'.
+func isMarkerName(name string) bool {
+	if name == "" || !isMarkerNameStart(rune(name[0])) {
+		return false
+	}
+	for _, segment := range strings.Split(name, ":") {
+		if segment == "" {
+			return false
+		}
+		for _, r := range segment {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-./", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func markerNamespace(name string) string {
+	if i := strings.IndexAny(name, ":= \t"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func markerKind(v interface{}) MarkerKind {
+	switch v.(type) {
+	case bool:
+		return MarkerBool
+	case string:
+		return MarkerString
+	case int:
+		return MarkerInt
+	case []interface{}:
+		return MarkerList
+	default:
+		return 0
+	}
+}
+
+// MarkerDefinition is the definition of the marker registered in the MarkerSchema.
+type MarkerDefinition struct {
+	Name string
+	// Value is the kind of the marker value. The markers without value are of the MarkerBool kind,
+	// which is also the kind of the zero Value.
+	Value MarkerKind
+	// Args are the kinds of the marker arguments mapped by their names. The zero kind is the MarkerBool.
+	Args map[string]MarkerKind
+}
+
+// String implements fmt.Stringer interface. The definition is written in the marker form with the value kinds
+// i.e.: 'gentools:index=string columns=list,unique=bool'.
+func (d MarkerDefinition) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(d.Name + "=" + d.Value.String())
+	names := make([]string, 0, len(d.Args))
+	for name := range d.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 {
+			sb.WriteRune(' ')
+		} else {
+			sb.WriteRune(',')
+		}
+		sb.WriteString(name + "=" + d.Args[name].String())
+	}
+	return sb.String()
+}
+
+// MarkerSchema is the registry of the known markers. The markers within the namespaces of the registered markers
+// are validated against their definitions, whereas the markers of other namespaces are not validated.
+// The zero value is ready to use.
+type MarkerSchema struct {
+	definitions map[string]MarkerDefinition
+	namespaces  map[string]struct{}
+}
+
+// Register registers given marker definitions.
+func (s *MarkerSchema) Register(definitions ...MarkerDefinition) error {
+	for _, def := range definitions {
+		if !isMarkerName(def.Name) {
+			return fmt.Errorf("invalid marker name: '%s'", def.Name)
+		}
+		if _, ok := s.definitions[def.Name]; ok {
+			return fmt.Errorf("marker: '%s' already registered", def.Name)
+		}
+		if s.definitions == nil {
+			s.definitions, s.namespaces = map[string]MarkerDefinition{}, map[string]struct{}{}
+		}
+		if def.Value == 0 {
+			def.Value = MarkerBool
+		}
+		s.definitions[def.Name] = def
+		s.namespaces[markerNamespace(def.Name)] = struct{}{}
+	}
+	return nil
+}
+
+// Definitions gets the registered marker definitions sorted by their names.
+func (s *MarkerSchema) Definitions() []MarkerDefinition {
+	definitions := make([]MarkerDefinition, 0, len(s.definitions))
+	for _, def := range s.definitions {
+		definitions = append(definitions, def)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions
+}
+
+// Covers checks if the marker with given name (or the marker text) is within the namespace of the registered markers.
+func (s *MarkerSchema) Covers(name string) bool {
+	_, ok := s.namespaces[markerNamespace(name)]
+	return ok
+}
+
+// Validate checks if the marker matches its registered definition. The values are converted to the kinds
+// of the definition, so that the string markers might have the int or bool like values, and the list markers
+// might have a single string or int value. The markers without value are malformed, unless of the MarkerBool kind.
+func (s *MarkerSchema) Validate(m Marker) (Marker, error) {
+	def, ok := s.definitions[m.Name]
+	if !ok {
+		return Marker{}, fmt.Errorf("unknown marker: '%s'", m.Name)
+	}
+	if !m.HasValue && def.Value != MarkerBool && def.Value != 0 {
+		return Marker{}, fmt.Errorf("marker: '%s' expects %s value", m.Name, def.Value)
+	}
+	v, err := convertMarkerValue(m.Value, def.Value)
+	if err != nil {
+		return Marker{}, fmt.Errorf("marker: '%s' %v", m.Name, err)
+	}
+	m.Value = v
+	if len(m.Args) == 0 {
+		return m, nil
+	}
+	args := make(map[string]interface{}, len(m.Args))
+	for name, value := range m.Args {
+		kind, ok := def.Args[name]
+		if !ok {
+			return Marker{}, fmt.Errorf("marker: '%s' unknown argument: '%s'", m.Name, name)
+		}
+		if args[name], err = convertMarkerValue(value, kind); err != nil {
+			return Marker{}, fmt.Errorf("marker: '%s' argument: '%s' %v", m.Name, name, err)
+		}
+	}
+	m.Args = args
+	return m, nil
+}
+
+func convertMarkerValue(v interface{}, kind MarkerKind) (interface{}, error) {
+	if kind == 0 {
+		kind = MarkerBool
+	}
+	actual := markerKind(v)
+	switch {
+	case actual == kind:
+		return v, nil
+	case kind == MarkerString && (actual == MarkerInt || actual == MarkerBool):
+		return fmt.Sprint(v), nil
+	case kind == MarkerList && (actual == MarkerString || actual == MarkerInt):
+		// The bool value is not wrapped, as it is the value of the marker or argument without the value.
+		return []interface{}{v}, nil
+	}
+	return nil, fmt.Errorf("expects %s value but is: %s", kind, actual)
+}
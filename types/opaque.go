@@ -0,0 +1,82 @@
+package types
+
+var _ Type = (*Opaque)(nil)
+
+// Opaque is the lightweight reference to the named type, that was not mapped by the parser i.e. the type
+// of the excluded package or the unexported type of the dependency mapped in the exported only mode.
+// It is identified by its package path and name, but doesn't provide the type definition.
+type Opaque struct {
+	// PkgPath is the import path of the package declaring the type.
+	PkgPath string
+	// Identifier is the identifier of the package declaring the type i.e.: 'http'.
+	Identifier string
+	// TypeName is the name of the type i.e.: 'Client'.
+	TypeName string
+	// TypeArgs are the type arguments of the instantiated generic type.
+	TypeArgs []Type
+	// UnderlyingKind is the kind of the underlying type i.e.: KindStruct.
+	UnderlyingKind Kind
+}
+
+// Name implements Type interface.
+func (o *Opaque) Name(identified bool, packageContext string) string {
+	name := o.TypeName + typeArgsName(nil, o.TypeArgs, identified, packageContext)
+	if identified && packageContext != o.PkgPath && o.Identifier != "" {
+		return o.Identifier + "." + name
+	}
+	return name
+}
+
+// FullName implements Type interface. It matches the full name of the type it refers to.
+func (o *Opaque) FullName() string {
+	return o.PkgPath + "/" + o.TypeName + typeArgsFullName(nil, o.TypeArgs)
+}
+
+// Kind implements Type interface.
+func (o *Opaque) Kind() Kind {
+	return KindOpaque
+}
+
+// Elem implements Type interface.
+func (o *Opaque) Elem() Type {
+	return nil
+}
+
+// String implements Type interface.
+func (o *Opaque) String() string {
+	return o.Name(true, "")
+}
+
+// Zero implements Type interface. The zero value is derived from the underlying kind, as the definition is not known.
+func (o *Opaque) Zero(identified bool, packageContext string) string {
+	switch k := o.UnderlyingKind; {
+	case k == KindBool:
+		return "false"
+	case k.IsBuiltin():
+		return BuiltInType{BuiltInKind: k}.Zero(identified, packageContext)
+	case k == KindStruct, k == KindArray:
+		return o.Name(identified, packageContext) + "{}"
+	case k == KindPtr, k == KindUnsafePointer, k == KindInterface, k == KindMap, k == KindSlice, k == KindChan, k == KindFunc:
+		return "nil"
+	default:
+		return "*new(" + o.Name(identified, packageContext) + ")"
+	}
+}
+
+// Equal implements Type interface. The opaque reference is equal to the named type it refers to.
+func (o *Opaque) Equal(another Type) bool {
+	switch at := Unalias(another).(type) {
+	case *Opaque:
+		return o.PkgPath == at.PkgPath && o.TypeName == at.TypeName && typeArgsEqual(o.TypeArgs, at.TypeArgs)
+	case *Struct, *Interface, *Alias:
+		return o.FullName() == at.FullName()
+	default:
+		return false
+	}
+}
+
+// isOpaqueOf checks if given type is the opaque reference to the named type.
+func isOpaqueOf(t Type, named Type) bool {
+	o, ok := Unalias(t).(*Opaque)
+	return ok && o.FullName() == named.FullName()
+}
@@ -0,0 +1,935 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EncodingVersion is the version of the serialized package form written by the EncodePackage.
+// The packages encoded with another version could not be decoded.
+const EncodingVersion = 10
+
+// EncodePackage writes the stable serialized form of the package. The types of the package are stored in a table
+// of nodes, so that the pointers shared within the package (i.e. recursive types and type parameters) are preserved.
+// The named types of other packages are referenced by their package path and name, thus the decoded package shares
+// these types with the packages decoded or parsed before it.
+func EncodePackage(w io.Writer, pkg *Package) error {
+	pkg.Lock()
+	e := &packageEncoder{pkg: pkg, index: map[Type]int{}}
+	ep := e.encodePackage()
+	pkg.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	return json.NewEncoder(w).Encode(ep)
+}
+
+// DecodePackage reads the package written by the EncodePackage. The types of other packages are resolved
+// within given package map, thus all the packages referenced by the encoded one needs to be already defined.
+// The decoded package is not added to the map.
+func DecodePackage(r io.Reader, pkgs PackageMap) (*Package, error) {
+	var ep encodedPackage
+	if err := json.NewDecoder(r).Decode(&ep); err != nil {
+		return nil, err
+	}
+	if ep.Version != EncodingVersion {
+		return nil, fmt.Errorf("unsupported package encoding version: %d", ep.Version)
+	}
+	d := &packageDecoder{pkg: NewPackage(ep.Path, ep.Identifier), pkgs: pkgs, nodes: ep.Nodes}
+	d.decodePackage(&ep)
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding package: %s failed: %w", ep.Path, d.err)
+	}
+	return d.pkg, nil
+}
+
+type encodedPackage struct {
+	Version      int
+	Path         string
+	Identifier   string
+	ForTest      string   `json:",omitempty"`
+	Imports      []string `json:",omitempty"`
+	Module       *Module  `json:",omitempty"`
+	Dir          string   `json:",omitempty"`
+	Comment      string   `json:",omitempty"`
+	Files        []*File  `json:",omitempty"`
+	Nodes        []encodedType
+	Types        map[string]string
+	Interfaces   []string             `json:",omitempty"`
+	Structs      []string             `json:",omitempty"`
+	Functions    []string             `json:",omitempty"`
+	Aliases      []string             `json:",omitempty"`
+	Declarations []encodedDeclaration `json:",omitempty"`
+	Unresolved   []string             `json:",omitempty"`
+	Examples     []encodedExample     `json:",omitempty"`
+}
+
+// encodedType is the node of the encoded types table. The references to the types are encoded as strings,
+// where '#<index>' is the node of the table, and '<path>.<name>' is the named type of another package.
+type encodedType struct {
+	Type            string          `json:"T"`
+	Pkg             string          `json:",omitempty"`
+	Name            string          `json:",omitempty"`
+	Comment         string          `json:",omitempty"`
+	Pos             *Position       `json:",omitempty"`
+	Builds          BuildContexts   `json:",omitempty"`
+	Constraint      string          `json:",omitempty"`
+	Kind            Kind            `json:",omitempty"`
+	Elem            string          `json:",omitempty"`
+	Key             string          `json:",omitempty"`
+	Size            int             `json:",omitempty"`
+	Dir             ChanDir         `json:",omitempty"`
+	Index           int             `json:",omitempty"`
+	Fields          []encodedField  `json:",omitempty"`
+	Func            *encodedFunc    `json:",omitempty"`
+	Methods         []encodedFunc   `json:",omitempty"`
+	ExplicitMethods []encodedFunc   `json:",omitempty"`
+	Embedded        []string        `json:",omitempty"`
+	Unions          [][]encodedTerm `json:",omitempty"`
+	Implicit        bool            `json:",omitempty"`
+	TypeAlias       bool            `json:",omitempty"`
+	Identifier      string          `json:",omitempty"`
+	TypeParams      []string        `json:",omitempty"`
+	TypeArgs        []string        `json:",omitempty"`
+	Origin          string          `json:",omitempty"`
+	TypeString      string          `json:",omitempty"`
+	Reason          string          `json:",omitempty"`
+	Reference       string          `json:",omitempty"`
+	Markers         []encodedMarker `json:",omitempty"`
+}
+
+// Enumerated encoded type nodes.
+const (
+	encodedBuiltIn    = "builtin"
+	encodedPointer    = "pointer"
+	encodedArray      = "array"
+	encodedMap        = "map"
+	encodedChan       = "chan"
+	encodedTypeParam  = "typeparam"
+	encodedUnresolved = "unresolved"
+	encodedStruct     = "struct"
+	encodedInterface  = "interface"
+	encodedAlias      = "alias"
+	encodedFunction   = "func"
+	encodedOpaque     = "opaque"
+)
+
+type encodedFunc struct {
+	Pkg        string          `json:",omitempty"`
+	Name       string          `json:",omitempty"`
+	Comment    string          `json:",omitempty"`
+	Line       string          `json:",omitempty"`
+	Receiver   *encodedParam   `json:",omitempty"`
+	In         []encodedParam  `json:",omitempty"`
+	Out        []encodedParam  `json:",omitempty"`
+	Variadic   bool            `json:",omitempty"`
+	TypeParams []string        `json:",omitempty"`
+	Pos        *Position       `json:",omitempty"`
+	Builds     BuildContexts   `json:",omitempty"`
+	Constraint string          `json:",omitempty"`
+	Markers    []encodedMarker `json:",omitempty"`
+}
+
+type encodedParam struct {
+	Name string `json:",omitempty"`
+	Type string
+}
+
+type encodedField struct {
+	Name      string `json:",omitempty"`
+	Comment   string `json:",omitempty"`
+	Line      string `json:",omitempty"`
+	Type      string
+	Tag       StructTag       `json:",omitempty"`
+	Index     []int           `json:",omitempty"`
+	Embedded  bool            `json:",omitempty"`
+	Anonymous bool            `json:",omitempty"`
+	Pos       *Position       `json:",omitempty"`
+	Markers   []encodedMarker `json:",omitempty"`
+}
+
+type encodedTerm struct {
+	Tilde bool `json:",omitempty"`
+	Type  string
+}
+
+type encodedDeclaration struct {
+	Name       string
+	Comment    string `json:",omitempty"`
+	Line       string `json:",omitempty"`
+	Type       string
+	Constant   bool             `json:",omitempty"`
+	Val        *encodedConstant `json:",omitempty"`
+	Pos        *Position        `json:",omitempty"`
+	Builds     BuildContexts    `json:",omitempty"`
+	Constraint string           `json:",omitempty"`
+	Markers    []encodedMarker  `json:",omitempty"`
+}
+
+// encodedMarker is the marker with its values. The JSON numbers of the values are decoded back as ints.
+type encodedMarker struct {
+	Name     string
+	Value    interface{}
+	Args     map[string]interface{} `json:",omitempty"`
+	Text     string
+	Pos      *Position `json:",omitempty"`
+	HasValue bool      `json:",omitempty"`
+}
+
+// encodedConstant is the exact constant value. The floating point numbers are encoded as 'numerator/denominator'
+// fractions, and the complex numbers have their imaginary part defined separately.
+type encodedConstant struct {
+	Kind  constant.Kind
+	Value string
+	Imag  string `json:",omitempty"`
+}
+
+type encodedExample struct {
+	Name        string
+	Comment     string `json:",omitempty"`
+	Output      string `json:",omitempty"`
+	Unordered   bool   `json:",omitempty"`
+	EmptyOutput bool   `json:",omitempty"`
+	Func        string
+	Pos         *Position `json:",omitempty"`
+}
+
+type packageEncoder struct {
+	pkg   *Package
+	nodes []encodedType
+	index map[Type]int
+	err   error
+}
+
+func (e *packageEncoder) encodePackage() *encodedPackage {
+	ep := &encodedPackage{
+		Version:    EncodingVersion,
+		Path:       e.pkg.Path,
+		Identifier: e.pkg.Identifier,
+		ForTest:    e.pkg.ForTest,
+		Imports:    e.pkg.Imports,
+		Module:     e.pkg.Module,
+		Dir:        e.pkg.Dir,
+		Comment:    e.pkg.Comment,
+		Files:      e.pkg.Files,
+		Types:      make(map[string]string, len(e.pkg.Types)),
+	}
+	// The nodes are added in a deterministic order, so that the same package is always encoded the same way.
+	names := make([]string, 0, len(e.pkg.Types))
+	for name := range e.pkg.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ep.Types[name] = e.ref(e.pkg.Types[name])
+	}
+	for _, it := range e.pkg.Interfaces {
+		ep.Interfaces = append(ep.Interfaces, e.ref(it))
+	}
+	for _, st := range e.pkg.Structs {
+		ep.Structs = append(ep.Structs, e.ref(st))
+	}
+	for _, ft := range e.pkg.Functions {
+		ep.Functions = append(ep.Functions, e.ref(ft))
+	}
+	for _, at := range e.pkg.Aliases {
+		ep.Aliases = append(ep.Aliases, e.ref(at))
+	}
+
+	names = names[:0]
+	for name := range e.pkg.Declarations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		decl := e.pkg.Declarations[name]
+		ep.Declarations = append(ep.Declarations, encodedDeclaration{
+			Name:       decl.Name,
+			Comment:    decl.Comment,
+			Line:       decl.LineComment,
+			Type:       e.ref(decl.Type),
+			Constant:   decl.Constant,
+			Val:        encodeConstant(decl.Val),
+			Pos:        encodePosition(decl.Pos),
+			Builds:     decl.Builds,
+			Constraint: decl.BuildConstraint,
+			Markers:    encodeMarkers(decl.Markers),
+		})
+	}
+	for _, u := range e.pkg.Unresolved {
+		ep.Unresolved = append(ep.Unresolved, e.ref(u))
+	}
+	for _, ex := range e.pkg.Examples {
+		var fn string
+		if ex.Func != nil {
+			fn = e.ref(ex.Func)
+		}
+		ep.Examples = append(ep.Examples, encodedExample{
+			Name:        ex.Name,
+			Comment:     ex.Comment,
+			Output:      ex.Output,
+			Unordered:   ex.Unordered,
+			EmptyOutput: ex.EmptyOutput,
+			Func:        fn,
+			Pos:         encodePosition(ex.Pos),
+		})
+	}
+	ep.Nodes = e.nodes
+	return ep
+}
+
+// ref gets the encoded reference of given type.
+func (e *packageEncoder) ref(t Type) string {
+	if t == nil {
+		return ""
+	}
+	if path, name, ok := e.external(t); ok {
+		return path + "." + name
+	}
+	i, ok := e.index[t]
+	if !ok {
+		// The index is set before the node is encoded, so that the recursive references are resolved.
+		i = len(e.nodes)
+		e.index[t] = i
+		e.nodes = append(e.nodes, encodedType{})
+		node := e.node(t)
+		e.nodes[i] = node
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// external gets the package path and the name of the type, if given type is a named type of another package.
+func (e *packageEncoder) external(t Type) (string, string, bool) {
+	var (
+		pkg  *Package
+		name string
+	)
+	switch x := t.(type) {
+	case *Struct:
+		pkg, name = x.Pkg, x.TypeName
+	case *Interface:
+		pkg, name = x.Pkg, x.InterfaceName
+	case *Alias:
+		pkg, name = x.Pkg, x.AliasName
+	case *Function:
+		pkg, name = x.Pkg, x.FuncName
+	}
+	if pkg == nil || pkg == e.pkg || name == "" {
+		return "", "", false
+	}
+	pkg.Lock()
+	named, ok := pkg.Types[name]
+	pkg.Unlock()
+	// The instances of generic types shares the name of their origin, but are not stored in the package.
+	if !ok || named != t {
+		return "", "", false
+	}
+	return pkg.Path, name, true
+}
+
+func (e *packageEncoder) node(t Type) encodedType {
+	switch x := t.(type) {
+	case *BuiltInType:
+		return encodedType{Type: encodedBuiltIn, Kind: x.BuiltInKind}
+	case *Pointer:
+		return encodedType{Type: encodedPointer, Elem: e.ref(x.PointedType)}
+	case *Array:
+		return encodedType{Type: encodedArray, Kind: x.ArrayKind, Size: x.ArraySize, Elem: e.ref(x.Type)}
+	case *Map:
+		return encodedType{Type: encodedMap, Key: e.ref(x.Key), Elem: e.ref(x.Value)}
+	case *Chan:
+		return encodedType{Type: encodedChan, Dir: x.Dir, Elem: e.ref(x.Type)}
+	case *TypeParam:
+		return encodedType{Type: encodedTypeParam, Name: x.ParamName, Index: x.Index, Elem: e.ref(x.Constraint)}
+	case *Unresolved:
+		return encodedType{
+			Type:       encodedUnresolved,
+			Pkg:        packagePath(x.Pkg),
+			Pos:        encodePosition(x.Pos),
+			TypeString: x.TypeString,
+			Reason:     x.Reason,
+			Reference:  x.Reference,
+		}
+	case *Struct:
+		n := encodedType{
+			Type:       encodedStruct,
+			Pkg:        packagePath(x.Pkg),
+			Name:       x.TypeName,
+			Comment:    x.Comment,
+			Pos:        encodePosition(x.Pos),
+			Builds:     x.Builds,
+			Constraint: x.BuildConstraint,
+			Methods:    e.functions(x.Methods),
+			TypeParams: e.typeParams(x.TypeParams),
+			TypeArgs:   e.refs(x.TypeArgs),
+			Markers:    encodeMarkers(x.Markers),
+		}
+		for _, field := range x.Fields {
+			n.Fields = append(n.Fields, encodedField{
+				Name:      field.Name,
+				Comment:   field.Comment,
+				Line:      field.LineComment,
+				Type:      e.ref(field.Type),
+				Tag:       field.Tag,
+				Index:     field.Index,
+				Embedded:  field.Embedded,
+				Anonymous: field.Anonymous,
+				Pos:       encodePosition(field.Pos),
+				Markers:   encodeMarkers(field.Markers),
+			})
+		}
+		if x.Origin != nil {
+			n.Origin = e.ref(x.Origin)
+		}
+		return n
+	case *Interface:
+		n := encodedType{
+			Type:            encodedInterface,
+			Pkg:             packagePath(x.Pkg),
+			Name:            x.InterfaceName,
+			Comment:         x.Comment,
+			Pos:             encodePosition(x.Pos),
+			Builds:          x.Builds,
+			Constraint:      x.BuildConstraint,
+			Methods:         e.functions(x.Methods),
+			ExplicitMethods: e.functions(x.ExplicitMethods),
+			Embedded:        e.refs(x.Embedded),
+			Implicit:        x.Implicit,
+			TypeParams:      e.typeParams(x.TypeParams),
+			TypeArgs:        e.refs(x.TypeArgs),
+			Markers:         encodeMarkers(x.Markers),
+		}
+		for _, union := range x.Unions {
+			terms := make([]encodedTerm, len(union.Terms))
+			for i, term := range union.Terms {
+				terms[i] = encodedTerm{Tilde: term.Tilde, Type: e.ref(term.Type)}
+			}
+			n.Unions = append(n.Unions, terms)
+		}
+		if x.Origin != nil {
+			n.Origin = e.ref(x.Origin)
+		}
+		return n
+	case *Alias:
+		n := encodedType{
+			Type:       encodedAlias,
+			Pkg:        packagePath(x.Pkg),
+			Name:       x.AliasName,
+			Comment:    x.Comment,
+			Pos:        encodePosition(x.Pos),
+			Builds:     x.Builds,
+			Constraint: x.BuildConstraint,
+			Elem:       e.ref(x.Type),
+			TypeAlias:  x.TypeAlias,
+			Methods:    e.functions(x.Methods),
+			TypeParams: e.typeParams(x.TypeParams),
+			TypeArgs:   e.refs(x.TypeArgs),
+			Markers:    encodeMarkers(x.Markers),
+		}
+		if x.Origin != nil {
+			n.Origin = e.ref(x.Origin)
+		}
+		return n
+	case *Function:
+		return encodedType{Type: encodedFunction, Func: e.function(x)}
+	case *Opaque:
+		return encodedType{
+			Type:       encodedOpaque,
+			Pkg:        x.PkgPath,
+			Identifier: x.Identifier,
+			Name:       x.TypeName,
+			TypeArgs:   e.refs(x.TypeArgs),
+			Kind:       x.UnderlyingKind,
+		}
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("unsupported type to encode: %T", t)
+		}
+		return encodedType{}
+	}
+}
+
+func (e *packageEncoder) function(f *Function) *encodedFunc {
+	ef := &encodedFunc{
+		Pkg:        packagePath(f.Pkg),
+		Name:       f.FuncName,
+		Comment:    f.Comment,
+		Line:       f.LineComment,
+		In:         e.params(f.In),
+		Out:        e.params(f.Out),
+		Variadic:   f.Variadic,
+		TypeParams: e.typeParams(f.TypeParams),
+		Pos:        encodePosition(f.Pos),
+		Builds:     f.Builds,
+		Constraint: f.BuildConstraint,
+		Markers:    encodeMarkers(f.Markers),
+	}
+	if f.Receiver != nil {
+		ef.Receiver = &encodedParam{Name: f.Receiver.Name, Type: e.ref(f.Receiver.Type)}
+	}
+	return ef
+}
+
+func (e *packageEncoder) functions(functions []Function) []encodedFunc {
+	var result []encodedFunc
+	for i := range functions {
+		result = append(result, *e.function(&functions[i]))
+	}
+	return result
+}
+
+func (e *packageEncoder) params(params []FuncParam) []encodedParam {
+	var result []encodedParam
+	for _, param := range params {
+		result = append(result, encodedParam{Name: param.Name, Type: e.ref(param.Type)})
+	}
+	return result
+}
+
+func (e *packageEncoder) typeParams(params TypeParams) []string {
+	var result []string
+	for _, param := range params {
+		result = append(result, e.ref(param))
+	}
+	return result
+}
+
+func (e *packageEncoder) refs(list []Type) []string {
+	var result []string
+	for _, t := range list {
+		result = append(result, e.ref(t))
+	}
+	return result
+}
+
+type packageDecoder struct {
+	pkg   *Package
+	pkgs  PackageMap
+	nodes []encodedType
+	types []Type
+	err   error
+}
+
+func (d *packageDecoder) decodePackage(ep *encodedPackage) {
+	d.pkg.ForTest, d.pkg.Imports, d.pkg.Module, d.pkg.Dir = ep.ForTest, ep.Imports, ep.Module, ep.Dir
+	d.pkg.Comment, d.pkg.Files = ep.Comment, ep.Files
+	// All the nodes are created before they are filled, so that the references to the following nodes are resolved.
+	d.types = make([]Type, len(d.nodes))
+	for i, n := range d.nodes {
+		d.types[i] = d.newType(n)
+	}
+	for i := range d.nodes {
+		d.fill(d.types[i], &d.nodes[i])
+	}
+
+	for name, ref := range ep.Types {
+		d.pkg.Types[name] = d.ref(ref)
+	}
+	for _, ref := range ep.Interfaces {
+		if it, ok := d.ref(ref).(*Interface); ok {
+			d.pkg.Interfaces = append(d.pkg.Interfaces, it)
+		}
+	}
+	for _, ref := range ep.Structs {
+		if st, ok := d.ref(ref).(*Struct); ok {
+			d.pkg.Structs = append(d.pkg.Structs, st)
+		}
+	}
+	for _, ref := range ep.Functions {
+		if ft, ok := d.ref(ref).(*Function); ok {
+			d.pkg.Functions = append(d.pkg.Functions, ft)
+		}
+	}
+	for _, ref := range ep.Aliases {
+		if at, ok := d.ref(ref).(*Alias); ok {
+			d.pkg.Aliases = append(d.pkg.Aliases, at)
+		}
+	}
+	for _, decl := range ep.Declarations {
+		val, err := decodeConstant(decl.Val)
+		if err != nil {
+			d.fail(err)
+		}
+		d.pkg.Declarations[decl.Name] = Declaration{
+			Comment:         decl.Comment,
+			LineComment:     decl.Line,
+			Name:            decl.Name,
+			Type:            d.ref(decl.Type),
+			Constant:        decl.Constant,
+			Val:             val,
+			Package:         d.pkg,
+			Pos:             decodePosition(decl.Pos),
+			Builds:          decl.Builds,
+			Markers:         decodeMarkers(decl.Markers),
+			BuildConstraint: decl.Constraint,
+		}
+	}
+	for _, ref := range ep.Unresolved {
+		if u, ok := d.ref(ref).(*Unresolved); ok {
+			d.pkg.Unresolved = append(d.pkg.Unresolved, u)
+		}
+	}
+	for _, ex := range ep.Examples {
+		fn, _ := d.ref(ex.Func).(*Function)
+		d.pkg.Examples = append(d.pkg.Examples, &Example{
+			Name:        ex.Name,
+			Comment:     ex.Comment,
+			Output:      ex.Output,
+			Unordered:   ex.Unordered,
+			EmptyOutput: ex.EmptyOutput,
+			Func:        fn,
+			Pos:         decodePosition(ex.Pos),
+		})
+	}
+}
+
+func (d *packageDecoder) newType(n encodedType) Type {
+	switch n.Type {
+	case encodedBuiltIn:
+		// The built in types are shared with the built in package.
+		if t, ok := GetBuiltInType(n.Kind.BuiltInName()); ok {
+			return t
+		}
+		return &BuiltInType{BuiltInKind: n.Kind}
+	case encodedPointer:
+		return &Pointer{}
+	case encodedArray:
+		return &Array{}
+	case encodedMap:
+		return &Map{}
+	case encodedChan:
+		return &Chan{}
+	case encodedTypeParam:
+		return &TypeParam{}
+	case encodedUnresolved:
+		return &Unresolved{}
+	case encodedStruct:
+		return &Struct{}
+	case encodedInterface:
+		return &Interface{}
+	case encodedAlias:
+		return &Alias{}
+	case encodedFunction:
+		return &Function{}
+	case encodedOpaque:
+		return &Opaque{}
+	default:
+		d.fail(fmt.Errorf("unknown encoded type: '%s'", n.Type))
+		return nil
+	}
+}
+
+func (d *packageDecoder) fill(t Type, n *encodedType) {
+	switch x := t.(type) {
+	case *Pointer:
+		x.PointedType = d.ref(n.Elem)
+	case *Array:
+		x.ArrayKind, x.ArraySize, x.Type = n.Kind, n.Size, d.ref(n.Elem)
+	case *Map:
+		x.Key, x.Value = d.ref(n.Key), d.ref(n.Elem)
+	case *Chan:
+		x.Dir, x.Type = n.Dir, d.ref(n.Elem)
+	case *TypeParam:
+		x.ParamName, x.Index, x.Constraint = n.Name, n.Index, d.ref(n.Elem)
+	case *Unresolved:
+		x.Pkg = d.packageOf(n.Pkg)
+		x.TypeString, x.Reason, x.Reference = n.TypeString, n.Reason, n.Reference
+		x.Pos = decodePosition(n.Pos)
+	case *Struct:
+		x.Pkg = d.packageOf(n.Pkg)
+		x.TypeName, x.Comment, x.Pos, x.Builds = n.Name, n.Comment, decodePosition(n.Pos), n.Builds
+		x.Markers, x.BuildConstraint = decodeMarkers(n.Markers), n.Constraint
+		for _, field := range n.Fields {
+			x.Fields = append(x.Fields, StructField{
+				Name:        field.Name,
+				Comment:     field.Comment,
+				LineComment: field.Line,
+				Type:        d.ref(field.Type),
+				Tag:         field.Tag,
+				Index:       field.Index,
+				Embedded:    field.Embedded,
+				Anonymous:   field.Anonymous,
+				Pos:         decodePosition(field.Pos),
+				Markers:     decodeMarkers(field.Markers),
+			})
+		}
+		x.Methods = d.functions(n.Methods)
+		x.TypeParams = d.typeParams(n.TypeParams)
+		x.TypeArgs = d.refs(n.TypeArgs)
+		if n.Origin != "" {
+			x.Origin, _ = d.ref(n.Origin).(*Struct)
+		}
+	case *Interface:
+		x.Pkg = d.packageOf(n.Pkg)
+		x.InterfaceName, x.Comment, x.Pos, x.Builds = n.Name, n.Comment, decodePosition(n.Pos), n.Builds
+		x.Markers, x.BuildConstraint = decodeMarkers(n.Markers), n.Constraint
+		x.Methods = d.functions(n.Methods)
+		x.ExplicitMethods = d.functions(n.ExplicitMethods)
+		x.Embedded = d.refs(n.Embedded)
+		for _, terms := range n.Unions {
+			union := Union{Terms: make([]Term, len(terms))}
+			for i, term := range terms {
+				union.Terms[i] = Term{Tilde: term.Tilde, Type: d.ref(term.Type)}
+			}
+			x.Unions = append(x.Unions, union)
+		}
+		x.Implicit = n.Implicit
+		x.TypeParams = d.typeParams(n.TypeParams)
+		x.TypeArgs = d.refs(n.TypeArgs)
+		if n.Origin != "" {
+			x.Origin, _ = d.ref(n.Origin).(*Interface)
+		}
+	case *Alias:
+		x.Pkg = d.packageOf(n.Pkg)
+		x.AliasName, x.Comment, x.Pos, x.Builds = n.Name, n.Comment, decodePosition(n.Pos), n.Builds
+		x.Markers, x.BuildConstraint = decodeMarkers(n.Markers), n.Constraint
+		x.Type, x.TypeAlias = d.ref(n.Elem), n.TypeAlias
+		x.Methods = d.functions(n.Methods)
+		x.TypeParams = d.typeParams(n.TypeParams)
+		x.TypeArgs = d.refs(n.TypeArgs)
+		if n.Origin != "" {
+			x.Origin, _ = d.ref(n.Origin).(*Alias)
+		}
+	case *Function:
+		if n.Func != nil {
+			d.function(x, n.Func)
+		}
+	case *Opaque:
+		// The package of the opaque type is not mapped, thus only its path is decoded.
+		x.PkgPath, x.Identifier, x.TypeName, x.UnderlyingKind = n.Pkg, n.Identifier, n.Name, n.Kind
+		x.TypeArgs = d.refs(n.TypeArgs)
+	}
+}
+
+func (d *packageDecoder) function(f *Function, ef *encodedFunc) {
+	f.Pkg = d.packageOf(ef.Pkg)
+	f.FuncName, f.Comment, f.LineComment, f.Variadic = ef.Name, ef.Comment, ef.Line, ef.Variadic
+	f.In, f.Out = d.params(ef.In), d.params(ef.Out)
+	f.TypeParams = d.typeParams(ef.TypeParams)
+	f.Pos, f.Builds, f.Markers = decodePosition(ef.Pos), ef.Builds, decodeMarkers(ef.Markers)
+	f.BuildConstraint = ef.Constraint
+	if ef.Receiver != nil {
+		f.Receiver = &Receiver{Name: ef.Receiver.Name, Type: d.ref(ef.Receiver.Type)}
+	}
+}
+
+func (d *packageDecoder) functions(list []encodedFunc) []Function {
+	var result []Function
+	for i := range list {
+		var f Function
+		d.function(&f, &list[i])
+		result = append(result, f)
+	}
+	return result
+}
+
+func (d *packageDecoder) params(list []encodedParam) []FuncParam {
+	var result []FuncParam
+	for _, param := range list {
+		result = append(result, FuncParam{Name: param.Name, Type: d.ref(param.Type)})
+	}
+	return result
+}
+
+func (d *packageDecoder) typeParams(list []string) TypeParams {
+	var result TypeParams
+	for _, ref := range list {
+		tp, ok := d.ref(ref).(*TypeParam)
+		if !ok {
+			d.fail(fmt.Errorf("type parameter expected: %s", ref))
+			continue
+		}
+		result = append(result, tp)
+	}
+	return result
+}
+
+func (d *packageDecoder) refs(list []string) []Type {
+	var result []Type
+	for _, ref := range list {
+		result = append(result, d.ref(ref))
+	}
+	return result
+}
+
+// ref resolves the encoded type reference.
+func (d *packageDecoder) ref(ref string) Type {
+	if ref == "" {
+		return nil
+	}
+	if strings.HasPrefix(ref, "#") {
+		i, err := strconv.Atoi(ref[1:])
+		if err != nil || i < 0 || i >= len(d.types) {
+			d.fail(fmt.Errorf("invalid type reference: %s", ref))
+			return nil
+		}
+		return d.types[i]
+	}
+	i := strings.LastIndexByte(ref, '.')
+	if i == -1 {
+		d.fail(fmt.Errorf("invalid type reference: %s", ref))
+		return nil
+	}
+	pkg := d.packageOf(ref[:i])
+	if pkg == nil {
+		return nil
+	}
+	t, ok := pkg.GetType(ref[i+1:])
+	if !ok {
+		d.fail(fmt.Errorf("type: %s not found", ref))
+		return nil
+	}
+	return t
+}
+
+// packageOf gets the package with given path. The packages other than the decoded one needs to be defined in the map.
+func (d *packageDecoder) packageOf(path string) *Package {
+	switch path {
+	case "":
+		return nil
+	case d.pkg.Path:
+		return d.pkg
+	case builtIn.Path:
+		return builtIn
+	}
+	pkg, ok := d.pkgs[path]
+	if !ok {
+		d.fail(fmt.Errorf("package: %s not found", path))
+		return nil
+	}
+	return pkg
+}
+
+func (d *packageDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func packagePath(pkg *Package) string {
+	if pkg == nil {
+		return ""
+	}
+	return pkg.Path
+}
+
+func encodePosition(pos Position) *Position {
+	if pos == (Position{}) {
+		return nil
+	}
+	return &pos
+}
+
+func decodePosition(pos *Position) Position {
+	if pos == nil {
+		return Position{}
+	}
+	return *pos
+}
+
+func encodeMarkers(markers Markers) []encodedMarker {
+	var result []encodedMarker
+	for _, m := range markers {
+		result = append(result, encodedMarker{Name: m.Name, Value: m.Value, Args: m.Args, Text: m.Text, Pos: encodePosition(m.Pos), HasValue: m.HasValue})
+	}
+	return result
+}
+
+func decodeMarkers(list []encodedMarker) Markers {
+	var result Markers
+	for _, em := range list {
+		m := Marker{Name: em.Name, Value: decodeMarkerValue(em.Value), Text: em.Text, Pos: decodePosition(em.Pos), HasValue: em.HasValue}
+		for name, value := range em.Args {
+			if m.Args == nil {
+				m.Args = make(map[string]interface{}, len(em.Args))
+			}
+			m.Args[name] = decodeMarkerValue(value)
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// decodeMarkerValue converts the JSON decoded marker value back into its marker kind.
+func decodeMarkerValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float64:
+		return int(x)
+	case []interface{}:
+		for i := range x {
+			x[i] = decodeMarkerValue(x[i])
+		}
+		return x
+	}
+	return v
+}
+
+func encodeConstant(v constant.Value) *encodedConstant {
+	if v == nil {
+		return nil
+	}
+	c := &encodedConstant{Kind: v.Kind()}
+	switch v.Kind() {
+	case constant.Bool, constant.String, constant.Int:
+		c.Value = v.ExactString()
+	case constant.Float:
+		c.Value = encodeNumber(v)
+	case constant.Complex:
+		c.Value, c.Imag = encodeNumber(constant.Real(v)), encodeNumber(constant.Imag(v))
+	}
+	return c
+}
+
+// encodeNumber encodes the exact numeric value as the 'numerator/denominator' fraction.
+func encodeNumber(v constant.Value) string {
+	num, denom := constant.Num(v), constant.Denom(v)
+	if num.Kind() == constant.Unknown || denom.Kind() == constant.Unknown {
+		// The value is too large or small to be represented as a fraction.
+		return v.ExactString()
+	}
+	return num.ExactString() + "/" + denom.ExactString()
+}
+
+func decodeConstant(c *encodedConstant) (constant.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var v constant.Value
+	switch c.Kind {
+	case constant.Unknown:
+		return constant.MakeUnknown(), nil
+	case constant.Bool:
+		v = constant.MakeBool(c.Value == "true")
+	case constant.String:
+		v = constant.MakeFromLiteral(c.Value, token.STRING, 0)
+	case constant.Int:
+		v = constant.MakeFromLiteral(c.Value, token.INT, 0)
+	case constant.Float:
+		v = decodeNumber(c.Value)
+	case constant.Complex:
+		v = constant.BinaryOp(decodeNumber(c.Value), token.ADD, constant.MakeImag(decodeNumber(c.Imag)))
+	}
+	if v == nil || v.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("invalid encoded constant: %s", c.Value)
+	}
+	return v, nil
+}
+
+func decodeNumber(s string) constant.Value {
+	i := strings.IndexByte(s, '/')
+	if i == -1 {
+		return constant.MakeFromLiteral(s, token.FLOAT, 0)
+	}
+	num := constant.MakeFromLiteral(s[:i], token.INT, 0)
+	denom := constant.MakeFromLiteral(s[i+1:], token.INT, 0)
+	if num.Kind() == constant.Unknown || denom.Kind() == constant.Unknown {
+		return constant.MakeUnknown()
+	}
+	return constant.BinaryOp(num, token.QUO, denom)
+}
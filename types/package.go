@@ -18,9 +18,61 @@ type Package struct {
 	Aliases      []*Alias
 	Types        map[string]Type
 	Declarations map[string]Declaration
+	// Unresolved are the type references within the package that could not be resolved.
+	Unresolved []*Unresolved
+	// ForTest is the path of the package under test. It is defined for the package loaded along with its test files
+	// and for the external test package i.e.: 'foo_test'.
+	ForTest string
+	// Examples are the testable example functions declared in the package test files.
+	Examples []*Example
+	// Imports are the sorted paths of the packages imported by the package.
+	Imports []string
+	// Module is the module containing the package. It is nil for the standard library packages, the packages
+	// loaded outside of the modules and the lazily mapped dependencies.
+	Module *Module
+	// Dir is the directory containing the package files.
+	Dir string
+	// Comment is the package doc comment i.e. the one placed above the package clause in the 'doc.go' file.
+	Comment string
+	// Files are the parsed Go files of the package sorted by their paths. The files and the package comment
+	// are defined only if the package comments are parsed.
+	Files    []*File
+	resolver TypeResolver
 	sync.Mutex
 }
 
+// Module is the Go module containing the package.
+type Module struct {
+	Path    string
+	Version string
+	// Dir is the directory holding the module files. For the replaced modules it is the replacement directory.
+	Dir string
+	// Main states if the module is the main module of the load i.e. the one used by the go.work workspace.
+	Main bool
+}
+
+// TypeResolver lazily resolves the package types that were not mapped yet.
+type TypeResolver interface {
+	// ResolveType maps the package type with given name. It is expected to set the type
+	// in the package with the SetNamedType method.
+	ResolveType(name string) (Type, bool)
+}
+
+// SetTypeResolver sets the resolver used for lazy mapping of the package types.
+// The package types not found in the package are resolved on the first access.
+func (p *Package) SetTypeResolver(resolver TypeResolver) {
+	p.Lock()
+	defer p.Unlock()
+	p.resolver = resolver
+}
+
+// IsLazy checks if the package types are mapped lazily on the first access.
+func (p *Package) IsLazy() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.resolver != nil
+}
+
 // NewPackage creates new package definition.
 func NewPackage(pkgPath, identifier string) *Package {
 	return &Package{Path: pkgPath, Identifier: identifier, Types: map[string]Type{}, Declarations: map[string]Declaration{}}
@@ -95,9 +147,7 @@ func (p *Package) GetPkgPath() PkgPath {
 
 // MustGetType get the type with given 'name' from given package. If the type is not found the function panics.
 func (p *Package) MustGetType(name string) Type {
-	p.Lock()
-	defer p.Unlock()
-	t, ok := p.Types[name]
+	t, ok := p.GetType(name)
 	if !ok {
 		panic(fmt.Sprintf("Type: '%s' not found in the package: '%s'", name, p.Path))
 	}
@@ -110,18 +160,21 @@ func (p *Package) TypeOf(name string) (Type, bool) {
 }
 
 // GetType gets concurrently safe package type.
+// If the package is lazy, the type not mapped yet is resolved on the first access.
 func (p *Package) GetType(name string) (Type, bool) {
 	p.Lock()
-	defer p.Unlock()
 	t, ok := p.Types[name]
-	return t, ok
+	resolver := p.resolver
+	p.Unlock()
+	if ok || resolver == nil {
+		return t, ok
+	}
+	return resolver.ResolveType(name)
 }
 
 // GetInterfaceType gets the interface by it's name.
 func (p *Package) GetInterfaceType(name string) (*Interface, bool) {
-	p.Lock()
-	defer p.Unlock()
-	t, ok := p.Types[name]
+	t, ok := p.GetType(name)
 	if !ok {
 		return nil, false
 	}
@@ -131,9 +184,7 @@ func (p *Package) GetInterfaceType(name string) (*Interface, bool) {
 
 // GetStruct gets the struct type by it's name.
 func (p *Package) GetStruct(name string) (*Struct, bool) {
-	p.Lock()
-	defer p.Unlock()
-	t, ok := p.Types[name]
+	t, ok := p.GetType(name)
 	if !ok {
 		return nil, false
 	}
@@ -163,9 +214,7 @@ func (p *Package) MustFunction(name string) *Function {
 
 // GetFunction gets the function type by it's name.
 func (p *Package) GetFunction(name string) (*Function, bool) {
-	p.Lock()
-	defer p.Unlock()
-	t, ok := p.Types[name]
+	t, ok := p.GetType(name)
 	if !ok {
 		return nil, false
 	}
@@ -175,9 +224,7 @@ func (p *Package) GetFunction(name string) (*Function, bool) {
 
 // GetAlias gets the wrapped type by it's name.
 func (p *Package) GetAlias(name string) (*Alias, bool) {
-	p.Lock()
-	defer p.Unlock()
-	t, ok := p.Types[name]
+	t, ok := p.GetType(name)
 	if !ok {
 		return nil, false
 	}
@@ -185,6 +232,11 @@ func (p *Package) GetAlias(name string) (*Alias, bool) {
 	return s, ok
 }
 
+// IsExternalTest checks if given package is the external test package i.e.: 'foo_test'.
+func (p *Package) IsExternalTest() bool {
+	return p.ForTest != "" && p.ForTest != p.Path
+}
+
 // IsStandard checks if given package is a standard package.
 func (p *Package) IsStandard() bool {
 	return p == builtIn
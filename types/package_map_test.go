@@ -1,6 +1,10 @@
 package types
 
 import (
+	"bytes"
+	"go/constant"
+	"go/token"
+	"strings"
 	"testing"
 )
 
@@ -291,3 +295,98 @@ func testPackagaMapTypeOfBuiltInSimple(pkgMap PackageMap, tc Type, testCaseKinds
 		}
 	}
 }
+
+func TestEncodePackage(t *testing.T) {
+	dep := NewPackage("mytesting.com/package/dep", "dep")
+	base := &Struct{Pkg: dep, TypeName: "Base", Fields: []StructField{{Name: "ID", Type: Int}}}
+	dep.SetNamedType(base.TypeName, base)
+	pkgs := PackageMap{dep.Path: dep}
+
+	pkg := NewPackage("mytesting.com/package/pkg", "pkg")
+	node := &Struct{Pkg: pkg, TypeName: "Node", Comment: "Node is the list node.", Pos: Position{Filename: "pkg.go", Line: 3}}
+	node.Fields = []StructField{
+		{Name: "Next", Type: PointerTo(node), Index: []int{0}},
+		{Name: "Base", Type: base, Index: []int{1}, Embedded: true, Tag: `json:"base"`},
+		{Name: "Values", Type: SliceOf(Rune), Index: []int{2}},
+	}
+	node.Methods = []Function{{Pkg: pkg, FuncName: "Len", Receiver: &Receiver{Name: "n", Type: PointerTo(node)}, Out: []FuncParam{{Type: Int}}}}
+	pkg.SetNamedType(node.TypeName, node)
+
+	param := &TypeParam{ParamName: "T", Constraint: Any}
+	list := &Struct{Pkg: pkg, TypeName: "List", TypeParams: TypeParams{param}}
+	list.Fields = []StructField{{Name: "Items", Type: SliceOf(param)}}
+	pkg.SetNamedType(list.TypeName, list)
+	instance := &Struct{Pkg: pkg, TypeName: "List", TypeArgs: []Type{base}, Origin: list, Fields: []StructField{{Name: "Items", Type: SliceOf(base)}}}
+
+	if err := pkg.NewVariable("Ints", instance); err != nil {
+		t.Fatalf("creating variable failed: %v", err)
+	}
+	constants := map[string]constant.Value{
+		"Int":     constant.MakeInt64(-42),
+		"Float":   constant.MakeFloat64(1.5),
+		"Third":   constant.BinaryOp(constant.MakeInt64(1), token.QUO, constant.MakeInt64(3)),
+		"String":  constant.MakeString("quoted \"value\""),
+		"Bool":    constant.MakeBool(true),
+		"Complex": constant.BinaryOp(constant.MakeFloat64(0.5), token.ADD, constant.MakeImag(constant.MakeInt64(2))),
+	}
+	for name, val := range constants {
+		if err := pkg.NewConstant(name, Float64, val); err != nil {
+			t.Fatalf("creating constant failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodePackage(&buf, pkg); err != nil {
+		t.Fatalf("encoding package failed: %v", err)
+	}
+	encoded := buf.String()
+
+	decoded, err := DecodePackage(strings.NewReader(encoded), pkgs)
+	if err != nil {
+		t.Fatalf("decoding package failed: %v", err)
+	}
+
+	dn := decoded.MustStruct("Node")
+	if dn.Comment != node.Comment || dn.Pos != node.Pos || len(dn.Fields) != 3 {
+		t.Fatalf("decoded struct doesn't match: %v", dn)
+	}
+	if ptr, ok := dn.Fields[0].Type.(*Pointer); !ok || ptr.PointedType != dn {
+		t.Errorf("recursive reference is expected to point to the decoded struct: %v", dn.Fields[0].Type)
+	}
+	if dn.Fields[1].Type != base {
+		t.Errorf("reference to another package type is expected to be shared: %v", dn.Fields[1].Type)
+	}
+	if dn.Fields[2].Type.Elem() != Rune {
+		t.Errorf("built in types are expected to be shared: %v", dn.Fields[2].Type)
+	}
+	if len(dn.Methods) != 1 || dn.Methods[0].Receiver.Type.Elem() != dn {
+		t.Errorf("method receiver is expected to point to the decoded struct: %v", dn.Methods)
+	}
+
+	dl := decoded.MustStruct("List")
+	if dl.Fields[0].Type.Elem() != dl.TypeParams[0] {
+		t.Errorf("type parameter is expected to be shared: %v", dl.Fields[0].Type)
+	}
+	di, ok := decoded.Declarations["Ints"].Type.(*Struct)
+	if !ok || di.Origin != dl || di == dl || di.TypeArgs[0] != base {
+		t.Errorf("decoded generic instance doesn't match: %v", decoded.Declarations["Ints"].Type)
+	}
+	for name, val := range constants {
+		decl := decoded.Declarations[name]
+		if decl.Val == nil || decl.Val.Kind() != val.Kind() || !constant.Compare(decl.Val, token.EQL, val) {
+			t.Errorf("decoded constant %s doesn't match: %v != %v", name, decl.Val, val)
+		}
+	}
+
+	buf.Reset()
+	if err = EncodePackage(&buf, decoded); err != nil {
+		t.Fatalf("encoding decoded package failed: %v", err)
+	}
+	if buf.String() != encoded {
+		t.Errorf("encoding is expected to be stable:\n%s\n%s", encoded, buf.String())
+	}
+
+	if _, err = DecodePackage(strings.NewReader(encoded), PackageMap{}); err == nil {
+		t.Error("decoding package without its dependencies is expected to fail")
+	}
+}
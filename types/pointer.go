@@ -44,7 +44,7 @@ func (p *Pointer) Zero(_ bool, _ string) string {
 
 // Equal implements Type interface.
 func (p *Pointer) Equal(another Type) bool {
-	pt, ok := another.(*Pointer)
+	pt, ok := Unalias(another).(*Pointer)
 	if !ok {
 		return false
 	}
@@ -0,0 +1,125 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+
+	"github.com/kucjac/gentools/internal/astutil"
+)
+
+// Position is the source code position of the type, field, method or declaration.
+// The Line and Column points to the declared identifier, whereas the Offset and End defines the byte offset range
+// of the whole declaration within the file.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+	Offset   int
+	End      int
+	// Overlay is the content of the files loaded in place of the files on disk i.e. the parser LoadConfig Overlay.
+	// It is not encoded, thus the source of the decoded position is read from disk.
+	Overlay *Overlay `json:"-"`
+}
+
+// IsValid checks if the position is defined.
+func (p Position) IsValid() bool {
+	return p.Filename != "" && p.Line > 0
+}
+
+// HasRange checks if the position has the byte offset range of the declaration defined.
+func (p Position) HasRange() bool {
+	return p.End > p.Offset
+}
+
+// String implements fmt.Stringer interface. It returns the position in the 'file:line:column' form.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	s := p.Filename + ":" + strconv.Itoa(p.Line)
+	if p.Column > 0 {
+		s += ":" + strconv.Itoa(p.Column)
+	}
+	return s
+}
+
+// Source gets the exact source code snippet of the declaration at given position.
+// If the position doesn't have the byte offset range defined, the file is parsed in order to find
+// the declaration located at given line and column. The file content is taken from the position Overlay,
+// and read from disk only if the overlay doesn't contain the file.
+func (p Position) Source() ([]byte, error) {
+	if !p.IsValid() {
+		return nil, errors.New("source position is not defined")
+	}
+	src, err := p.Overlay.ReadFile(p.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := p.Offset, p.End
+	if !p.HasRange() {
+		if start, end, err = p.findRange(src); err != nil {
+			return nil, err
+		}
+	}
+	if start < 0 || end > len(src) {
+		return nil, fmt.Errorf("source range: %d-%d is out of the file: '%s' size", start, end, p.Filename)
+	}
+	return src[start:end], nil
+}
+
+// Overlay is the content of the source files, that replaces the files on disk.
+type Overlay struct {
+	files map[string][]byte
+}
+
+// NewOverlay creates the overlay of given files content, mapped by their absolute paths.
+// If there are no files, the result is nil.
+func NewOverlay(files map[string][]byte) *Overlay {
+	if len(files) == 0 {
+		return nil
+	}
+	return &Overlay{files: files}
+}
+
+// ReadFile reads the content of given file. The overlay content is preferred over the file on disk.
+// The nil overlay reads the file from disk.
+func (o *Overlay) ReadFile(fileName string) ([]byte, error) {
+	if o != nil {
+		if src, ok := o.files[fileName]; ok {
+			return src, nil
+		}
+	}
+	return os.ReadFile(fileName)
+}
+
+func (p Position) findRange(src []byte) (int, int, error) {
+	fs := token.NewFileSet()
+	f, err := parser.ParseFile(fs, p.Filename, src, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	var node ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if node != nil {
+			return false
+		}
+		for _, ident := range astutil.DeclaredIdents(n) {
+			pos := fs.Position(ident.Pos())
+			if pos.Line == p.Line && (p.Column == 0 || pos.Column == p.Column) {
+				node = n
+				return false
+			}
+		}
+		return true
+	})
+	if node == nil {
+		return 0, 0, fmt.Errorf("no declaration found at: %s", p)
+	}
+	return fs.Position(node.Pos()).Offset, fs.Position(node.End()).Offset, nil
+}
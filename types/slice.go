@@ -64,7 +64,7 @@ func (a *Array) Zero(identified bool, packageContext string) string {
 
 // Equal implements Type interface.
 func (a *Array) Equal(another Type) bool {
-	at, ok := another.(*Array)
+	at, ok := Unalias(another).(*Array)
 	if !ok {
 		return false
 	}
@@ -10,11 +10,26 @@ var _ Type = (*Struct)(nil)
 
 // Struct is the struct type reflection.
 type Struct struct {
-	Pkg      *Package
-	Comment  string
-	TypeName string
-	Fields   []StructField
-	Methods  []Function
+	Pkg        *Package
+	Comment    string
+	TypeName   string
+	Fields     []StructField
+	Methods    []Function
+	TypeParams TypeParams
+	// TypeArgs are the type arguments of the instantiated generic struct i.e.: 'List[int]'.
+	TypeArgs []Type
+	// Origin is the generic struct of given instantiated struct.
+	Origin *Struct
+	// Pos is the source position of the struct declaration.
+	Pos Position
+	// Builds are the build configurations under which the struct is declared.
+	Builds BuildContexts
+	// Markers are the structured annotations of the struct type i.e.: '// +gentools:table=users'.
+	Markers Markers
+	// BuildConstraint is the build constraint of the files declaring the struct under the loaded build contexts
+	// i.e.: 'linux || darwin'. It is empty if the struct is not constrained, or the packages were not loaded
+	// with the BuildContexts.
+	BuildConstraint string
 }
 
 // Implements checks if given structure implements provided interface.
@@ -24,17 +39,18 @@ func (s *Struct) Implements(interfaceType *Interface, pointer bool) bool {
 
 // Name implements Type interface.
 func (s *Struct) Name(identifier bool, packageContext string) string {
+	name := s.TypeName + typeArgsName(s.TypeParams, s.TypeArgs, identifier, packageContext)
 	if identifier && packageContext != s.Pkg.Path {
 		if i := s.Pkg.Identifier; i != "" {
-			return i + "." + s.TypeName
+			return i + "." + name
 		}
 	}
-	return s.TypeName
+	return name
 }
 
 // FullName implements Type interface.
 func (s *Struct) FullName() string {
-	return s.Pkg.Path + "/" + s.TypeName
+	return s.Pkg.Path + "/" + s.TypeName + typeArgsFullName(s.TypeParams, s.TypeArgs)
 }
 
 // Package implements Type interface.
@@ -64,11 +80,16 @@ func (s *Struct) Zero(identified bool, packageContext string) string {
 
 // Equal implements Type interface.
 func (s *Struct) Equal(another Type) bool {
-	st, ok := another.(*Struct)
+	st, ok := Unalias(another).(*Struct)
 	if !ok {
-		return false
+		return isOpaqueOf(another, s)
 	}
-	return st.Pkg == s.Pkg && st.TypeName == s.TypeName
+	return st.Pkg == s.Pkg && st.TypeName == s.TypeName && typeArgsEqual(st.TypeArgs, s.TypeArgs)
+}
+
+// IsGeneric checks if given struct is a generic type that is not instantiated.
+func (s *Struct) IsGeneric() bool {
+	return len(s.TypeParams) != 0 && len(s.TypeArgs) == 0
 }
 
 func (s *Struct) getMethods() []Function {
@@ -84,6 +105,12 @@ type StructField struct {
 	Index     []int
 	Embedded  bool
 	Anonymous bool
+	// Pos is the source position of the field.
+	Pos Position
+	// LineComment is the trailing comment of the field i.e.: 'ID int // The identifier.'.
+	LineComment string
+	// Markers are the structured annotations of the field.
+	Markers Markers
 }
 
 // KindString implements fmt.Stringer interface.
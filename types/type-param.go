@@ -0,0 +1,147 @@
+package types
+
+import (
+	"strings"
+)
+
+var _ Type = (*TypeParam)(nil)
+
+// TypeParam is the type parameter of the generic type or function.
+// I.e.: 'func Map[T, U any]()' would contain two TypeParams: 'T' and 'U' with the 'any' constraint.
+type TypeParam struct {
+	ParamName  string
+	Index      int
+	Constraint Type
+}
+
+// Name implements Type interface.
+func (t *TypeParam) Name(_ bool, _ string) string {
+	return t.ParamName
+}
+
+// FullName implements Type interface.
+func (t *TypeParam) FullName() string {
+	return t.ParamName
+}
+
+// Kind implements Type interface.
+func (t *TypeParam) Kind() Kind {
+	return KindTypeParam
+}
+
+// Elem implements Type interface.
+func (t *TypeParam) Elem() Type {
+	return nil
+}
+
+// String implements Type interface.
+func (t TypeParam) String() string {
+	return t.ParamName
+}
+
+// Zero implements Type interface.
+func (t *TypeParam) Zero(_ bool, _ string) string {
+	return "*new(" + t.ParamName + ")"
+}
+
+// Equal implements Type interface. The type parameters are equal only if these are the same parameter
+// of the same declaration, thus i.e. the 'T' of 'Foo[T]' is not equal to the 'T' of 'Bar[T]'.
+func (t *TypeParam) Equal(another Type) bool {
+	tp, ok := Unalias(another).(*TypeParam)
+	return ok && t == tp
+}
+
+// Declaration gets the type parameter declaration string i.e.: 'T comparable'.
+func (t *TypeParam) Declaration(identified bool, packageContext string) string {
+	if t.Constraint == nil {
+		return t.ParamName + " any"
+	}
+	return t.ParamName + " " + t.Constraint.Name(identified, packageContext)
+}
+
+// TypeParams is the list of type parameters of the generic type or function.
+type TypeParams []*TypeParam
+
+// Declaration gets the type parameters declaration string i.e.: '[K comparable, V any]'.
+// If the list is empty it returns an empty string.
+func (t TypeParams) Declaration(identified bool, packageContext string) string {
+	if len(t) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	for i, tp := range t {
+		sb.WriteString(tp.Declaration(identified, packageContext))
+		if i != len(t)-1 {
+			sb.WriteString(", ")
+		}
+	}
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+// typeArgsName gets the name of the type arguments of the instantiated type. For the generic type, that was not
+// instantiated it lists its type parameter names, so that it could be used as i.e. method receiver.
+func typeArgsName(params TypeParams, args []Type, identified bool, packageContext string) string {
+	if len(args) == 0 && len(params) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	if len(args) != 0 {
+		for i, arg := range args {
+			sb.WriteString(arg.Name(identified, packageContext))
+			if i != len(args)-1 {
+				sb.WriteString(", ")
+			}
+		}
+	} else {
+		for i, param := range params {
+			sb.WriteString(param.ParamName)
+			if i != len(params)-1 {
+				sb.WriteString(", ")
+			}
+		}
+	}
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+// typeArgsFullName gets the full name of the type arguments of the instantiated type.
+func typeArgsFullName(params TypeParams, args []Type) string {
+	if len(args) == 0 && len(params) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	if len(args) != 0 {
+		for i, arg := range args {
+			sb.WriteString(arg.FullName())
+			if i != len(args)-1 {
+				sb.WriteString(", ")
+			}
+		}
+	} else {
+		for i, param := range params {
+			sb.WriteString(param.ParamName)
+			if i != len(params)-1 {
+				sb.WriteString(", ")
+			}
+		}
+	}
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+// typeArgsEqual checks if the type arguments of two instantiated types are equal.
+func typeArgsEqual(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package types
+
+import (
+	"strings"
+)
+
+// Term is the single type term of the constraint interface union i.e.: '~int'.
+type Term struct {
+	// Tilde states if the term is an approximation term - the term matches all types with given underlying type.
+	Tilde bool
+	Type  Type
+}
+
+// Name gets the name of the term.
+func (t Term) Name(identified bool, packageContext string) string {
+	if t.Tilde {
+		return "~" + t.Type.Name(identified, packageContext)
+	}
+	return t.Type.Name(identified, packageContext)
+}
+
+// FullName gets the full name of the term.
+func (t Term) FullName() string {
+	if t.Tilde {
+		return "~" + t.Type.FullName()
+	}
+	return t.Type.FullName()
+}
+
+// String implements fmt.Stringer interface.
+func (t Term) String() string {
+	return t.Name(true, "")
+}
+
+// Contains checks if given type matches the term.
+func (t Term) Contains(tp Type) bool {
+	if it, ok := t.Type.(*Interface); ok {
+		return it.TypeSetContains(tp)
+	}
+	if t.Tilde {
+		return Underlying(tp).Equal(Underlying(t.Type))
+	}
+	return t.Type.Equal(tp)
+}
+
+// Union is the union of the type terms embedded in the constraint interface i.e.: '~int | ~int64 | float64'.
+type Union struct {
+	Terms []Term
+}
+
+// Name gets the name of the union.
+func (u Union) Name(identified bool, packageContext string) string {
+	sb := strings.Builder{}
+	for i, term := range u.Terms {
+		sb.WriteString(term.Name(identified, packageContext))
+		if i != len(u.Terms)-1 {
+			sb.WriteString(" | ")
+		}
+	}
+	return sb.String()
+}
+
+// FullName gets the full name of the union.
+func (u Union) FullName() string {
+	sb := strings.Builder{}
+	for i, term := range u.Terms {
+		sb.WriteString(term.FullName())
+		if i != len(u.Terms)-1 {
+			sb.WriteString(" | ")
+		}
+	}
+	return sb.String()
+}
+
+// String implements fmt.Stringer interface.
+func (u Union) String() string {
+	return u.Name(true, "")
+}
+
+// Contains checks if given type matches any of the union terms.
+func (u Union) Contains(tp Type) bool {
+	for _, term := range u.Terms {
+		if term.Contains(tp) {
+			return true
+		}
+	}
+	return false
+}
+
+// Underlying gets the underlying type of given type. The underlying type of the Alias is the underlying type
+// of the type it wraps. For all other types it is the type itself.
+func Underlying(t Type) Type {
+	for {
+		a, ok := t.(*Alias)
+		if !ok || a.Type == nil {
+			return t
+		}
+		t = a.Type
+	}
+}
+
+// IsComparable checks if the values of given type are comparable.
+func IsComparable(t Type) bool {
+	switch tt := Underlying(t).(type) {
+	case *Array:
+		if tt.ArrayKind == KindSlice {
+			return false
+		}
+		return IsComparable(tt.Type)
+	case *Map, *Function:
+		return false
+	case *Struct:
+		for _, field := range tt.Fields {
+			if !IsComparable(field.Type) {
+				return false
+			}
+		}
+		return true
+	case *TypeParam:
+		if it, ok := tt.Constraint.(*Interface); ok {
+			return it.isComparable()
+		}
+		return false
+	default:
+		return true
+	}
+}
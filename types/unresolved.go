@@ -0,0 +1,115 @@
+package types
+
+import (
+	"sort"
+)
+
+var _ Type = (*Unresolved)(nil)
+
+// Unresolved is the type that could not be resolved by the parser i.e.: the type of undefined identifier.
+// It takes place of the type reference (field, parameter, result), so that the rest of the declaration is usable.
+type Unresolved struct {
+	// Pkg is the package where the unresolved type is referenced.
+	Pkg *Package
+	// TypeString is the source expression of the type i.e.: 'Undefined'. If the syntax of the package is not loaded,
+	// it is the go/types string of the type.
+	TypeString string
+	// Reason states why the type could not be resolved.
+	Reason string
+	// Reference is the name of the declaration referencing the type i.e.: 'Foo.Field'.
+	Reference string
+	// Pos is the source position of the type reference.
+	Pos Position
+}
+
+// Name implements Type interface.
+func (u *Unresolved) Name(_ bool, _ string) string {
+	return u.TypeString
+}
+
+// FullName implements Type interface.
+func (u *Unresolved) FullName() string {
+	return u.TypeString
+}
+
+// Kind implements Type interface.
+func (u *Unresolved) Kind() Kind {
+	return KindUnresolved
+}
+
+// Elem implements Type interface.
+func (u *Unresolved) Elem() Type {
+	return nil
+}
+
+// String implements Type interface.
+func (u *Unresolved) String() string {
+	return u.TypeString
+}
+
+// Zero implements Type interface.
+func (u *Unresolved) Zero(_ bool, _ string) string {
+	return "*new(" + u.TypeString + ")"
+}
+
+// Equal implements Type interface. The unresolved types are never equal to the resolved ones.
+func (u *Unresolved) Equal(another Type) bool {
+	ut, ok := Unalias(another).(*Unresolved)
+	if !ok {
+		return false
+	}
+	return u.TypeString == ut.TypeString
+}
+
+// NewUnresolved adds new unresolved type reference to the package. If the package already contains the unresolved
+// type of given type string, reference and position i.e. of another generic type instance, the existing one is returned.
+func (p *Package) NewUnresolved(typeString, reason, reference string, pos Position) *Unresolved {
+	p.Lock()
+	defer p.Unlock()
+	if u, ok := p.getUnresolved(typeString, reference, pos); ok {
+		return u
+	}
+	u := &Unresolved{Pkg: p, TypeString: typeString, Reason: reason, Reference: reference, Pos: pos}
+	p.Unresolved = append(p.Unresolved, u)
+	return u
+}
+
+// GetUnresolved gets the unresolved type of given type string, reference and position. The single reference
+// might contain multiple unresolved types i.e.: 'map[UndefinedKey]UndefinedValue'.
+func (p *Package) GetUnresolved(typeString, reference string, pos Position) (*Unresolved, bool) {
+	p.Lock()
+	defer p.Unlock()
+	return p.getUnresolved(typeString, reference, pos)
+}
+
+func (p *Package) getUnresolved(typeString, reference string, pos Position) (*Unresolved, bool) {
+	for _, u := range p.Unresolved {
+		if u.TypeString == typeString && u.Reference == reference && u.Pos == pos {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// Unresolved lists all unresolved type references in the packages. The result is sorted by the package path
+// and the reference positions.
+func (p PackageMap) Unresolved() []*Unresolved {
+	var result []*Unresolved
+	for _, pkg := range p {
+		result = append(result, pkg.Unresolved...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := result[i], result[j]
+		if ri.Pkg.Path != rj.Pkg.Path {
+			return ri.Pkg.Path < rj.Pkg.Path
+		}
+		if ri.Pos.Filename != rj.Pos.Filename {
+			return ri.Pos.Filename < rj.Pos.Filename
+		}
+		if ri.Pos.Line != rj.Pos.Line {
+			return ri.Pos.Line < rj.Pos.Line
+		}
+		return ri.Reference < rj.Reference
+	})
+	return result
+}